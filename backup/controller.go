@@ -0,0 +1,90 @@
+package backup
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/thansetan/berak/helper"
+)
+
+type controller struct {
+	logger *slog.Logger
+	svc    *backupService
+}
+
+func NewController(svc *backupService, logger *slog.Logger) *controller {
+	return &controller{logger, svc}
+}
+
+// Download serves a fresh snapshot of the database rather than the live file,
+// which could be mid-write.
+func (c *controller) Download(w http.ResponseWriter, r *http.Request) {
+	path, err := c.svc.SnapshotFile(r.Context())
+	if errors.Is(err, errors.ErrUnsupported) {
+		helper.WriteMessage(w, http.StatusNotImplemented, "downloading is only supported for SQLite!")
+		return
+	}
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to take snapshot", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	defer os.Remove(path)
+
+	w.Header().Set("Content-Disposition", "attachment; filename=berak.sqlite3")
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeFile(w, r, path)
+}
+
+// maxRestoreSize is the largest database that can be uploaded to restore.
+const maxRestoreSize = 100 << 20
+
+// Restore replaces the database with the uploaded one, sent either as the body
+// or as the file field of a multipart form.
+func (c *controller) Restore(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRestoreSize)
+	defer r.Body.Close()
+
+	var src io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			helper.WriteMessage(w, http.StatusBadRequest, "file is required!")
+			return
+		}
+		defer f.Close()
+		src = f
+	}
+	data, err := io.ReadAll(src)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		helper.WriteMessage(w, http.StatusRequestEntityTooLarge, "backup is too large!")
+		return
+	}
+	if err != nil {
+		c.logger.InfoContext(r.Context(), "failed to read backup", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.WriteMessage(w, http.StatusBadRequest, "failed to read backup!")
+		return
+	}
+
+	err = c.svc.Restore(r.Context(), data)
+	if errors.Is(err, errors.ErrUnsupported) {
+		helper.WriteMessage(w, http.StatusNotImplemented, "restoring is only supported for SQLite!")
+		return
+	}
+	if errors.Is(err, ErrInvalidBackup) {
+		helper.WriteMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to restore backup", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	c.logger.InfoContext(r.Context(), "backup restored!", "remote_addr", r.RemoteAddr)
+	helper.WriteMessage(w, http.StatusOK, "backup restored!")
+}
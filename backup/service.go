@@ -0,0 +1,270 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thansetan/berak/db"
+)
+
+var ErrInvalidBackup = errors.New("invalid backup")
+
+const (
+	filePrefix   = "berak-"
+	fileExt      = ".sqlite3"
+	encryptedExt = ".enc"
+	// fileTimestamp is precise to the nanosecond, so a snapshot taken right
+	// after another, e.g. a manual one, doesn't replace it.
+	fileTimestamp = "20060102T150405.000000000Z"
+)
+
+// backupService only supports SQLite, and returns errors.ErrUnsupported for
+// other databases, which have their own tools.
+type backupService struct {
+	db     *db.Conn
+	dir    string
+	keep   int
+	logger *slog.Logger
+	// passphrase encrypts the snapshots kept in dir. They're stored as is when
+	// it's empty.
+	passphrase string
+	// mu keeps two snapshots from being taken into the same file.
+	mu sync.Mutex
+}
+
+func NewService(db *db.Conn, dir string, keep int, passphrase string, logger *slog.Logger) *backupService {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &backupService{db: db, dir: dir, keep: keep, passphrase: passphrase, logger: logger}
+}
+
+// Run takes a snapshot every interval until ctx is done.
+func (s *backupService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			path, err := s.Snapshot(ctx)
+			if err != nil {
+				s.logger.ErrorContext(ctx, "failed to take snapshot!", "error", err)
+				break
+			}
+			s.logger.InfoContext(ctx, "snapshot taken!", "path", path)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Snapshot stores a consistent copy of the database in the backup directory,
+// encrypted if there's a passphrase, and removes the oldest ones beyond the
+// number to keep.
+func (s *backupService) Snapshot(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db.Driver() != db.DriverSQLite {
+		return "", fmt.Errorf("snapshot %s database: %w", s.db.Driver(), errors.ErrUnsupported)
+	}
+	err := os.MkdirAll(s.dir, 0o700)
+	if err != nil {
+		return "", fmt.Errorf("create backup directory: %w", err)
+	}
+	name := filePrefix + time.Now().UTC().Format(fileTimestamp) + fileExt
+	if s.passphrase != "" {
+		name += encryptedExt
+	}
+	path := filepath.Join(s.dir, name)
+
+	var tmp string
+	if s.passphrase == "" {
+		tmp, err = s.vacuumInto(ctx, s.dir)
+	} else {
+		tmp, err = s.encryptedSnapshot(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp)
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return "", fmt.Errorf("move snapshot: %w", err)
+	}
+
+	err = s.prune()
+	if err != nil {
+		return path, fmt.Errorf("prune snapshots: %w", err)
+	}
+	return path, nil
+}
+
+// encryptedSnapshot writes an encrypted snapshot to a temporary file in the
+// backup directory. The plain copy it's encrypted from is taken in a directory
+// of its own next to the database, so it never ends up among the encrypted
+// backups, even if encrypting fails or berak stops halfway.
+func (s *backupService) encryptedSnapshot(ctx context.Context) (string, error) {
+	private, err := os.MkdirTemp(filepath.Dir(s.db.Path()), filePrefix+"snapshot-*")
+	if err != nil {
+		return "", fmt.Errorf("create snapshot directory: %w", err)
+	}
+	defer os.RemoveAll(private)
+	plain, err := s.vacuumInto(ctx, private)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(plain)
+	if err != nil {
+		return "", fmt.Errorf("read snapshot: %w", err)
+	}
+	data, err = encrypt(data, s.passphrase)
+	if err != nil {
+		return "", fmt.Errorf("encrypt snapshot: %w", err)
+	}
+
+	f, err := os.CreateTemp(s.dir, filePrefix+"*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("create snapshot file: %w", err)
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write snapshot: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// SnapshotFile writes a consistent, unencrypted copy of the database to a
+// temporary file, which the caller has to remove.
+func (s *backupService) SnapshotFile(ctx context.Context) (string, error) {
+	return s.vacuumInto(ctx, os.TempDir())
+}
+
+// Restore replaces the database with the one in data, which may be an
+// encrypted snapshot, after validating it and taking a snapshot of the current
+// one. The tokens, sessions and share links of the current database are kept.
+func (s *backupService) Restore(ctx context.Context, data []byte) error {
+	if s.db.Driver() != db.DriverSQLite {
+		return fmt.Errorf("restore %s database: %w", s.db.Driver(), errors.ErrUnsupported)
+	}
+	if IsEncrypted(data) {
+		if s.passphrase == "" {
+			return fmt.Errorf("%w: backup is encrypted but no passphrase is set", ErrInvalidBackup)
+		}
+		var err error
+		data, err = Decrypt(data, s.passphrase)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+		}
+	}
+
+	// the new file has to be on the same filesystem as the database to be
+	// renamed over it.
+	f, err := os.CreateTemp(filepath.Dir(s.db.Path()), filePrefix+"restore-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create restore file: %w", err)
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write restore file: %w", err)
+	}
+
+	err = db.Validate(ctx, path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidBackup, err)
+	}
+
+	safety, err := s.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("take safety snapshot: %w", err)
+	}
+	s.logger.InfoContext(ctx, "safety snapshot taken!", "path", safety)
+
+	err = s.db.Swap(path, db.AuthTables...)
+	if err != nil {
+		return fmt.Errorf("swap database: %w", err)
+	}
+	return nil
+}
+
+// List returns the paths of the kept snapshots, oldest first.
+func (s *backupService) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, filePrefix) || !(strings.HasSuffix(name, fileExt) || strings.HasSuffix(name, fileExt+encryptedExt)) {
+			continue
+		}
+		paths = append(paths, filepath.Join(s.dir, name))
+	}
+	// the names start with the time they were taken at.
+	slices.Sort(paths)
+	return paths, nil
+}
+
+func (s *backupService) prune() error {
+	if s.keep <= 0 {
+		return nil
+	}
+	paths, err := s.List()
+	if err != nil {
+		return err
+	}
+	for len(paths) > s.keep {
+		err = os.Remove(paths[0])
+		if err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// vacuumInto copies the database into a new file in dir. Unlike copying the
+// file, VACUUM INTO reads within a transaction, so concurrent writes can't
+// tear the copy.
+func (s *backupService) vacuumInto(ctx context.Context, dir string) (string, error) {
+	if s.db.Driver() != db.DriverSQLite {
+		return "", fmt.Errorf("snapshot %s database: %w", s.db.Driver(), errors.ErrUnsupported)
+	}
+	f, err := os.CreateTemp(dir, filePrefix+"*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("create snapshot file: %w", err)
+	}
+	path := f.Name()
+	f.Close()
+	// VACUUM INTO refuses to overwrite a file, even an empty one.
+	os.Remove(path)
+
+	_, err = s.db.ExecContext(ctx, `VACUUM INTO ?`, path)
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("vacuum into: %w", err)
+	}
+	return path, nil
+}
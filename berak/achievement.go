@@ -0,0 +1,142 @@
+package berak
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thansetan/berak/model"
+)
+
+type achievementStats struct {
+	Total                 int
+	LongestPoopStreak     model.PoopStreak
+	LongestDayWithoutPoop model.LongestDayWithoutPoop
+	MostPoopInADay        model.MostPoopInADate
+}
+
+type badge struct {
+	model.Badge
+	unlocked func(achievementStats) bool
+}
+
+// badges is the registry of every badge that can be unlocked, in the order
+// they are shown on the badges page.
+var badges = []badge{
+	{
+		Badge: model.Badge{ID: "first_poop", Emoji: "🎉", Name: "First Drop", Description: "Drop your very first 💩."},
+		unlocked: func(s achievementStats) bool {
+			return s.Total >= 1
+		},
+	},
+	{
+		Badge: model.Badge{ID: "total_100", Emoji: "💯", Name: "Centurion", Description: "Drop 100 💩s in total."},
+		unlocked: func(s achievementStats) bool {
+			return s.Total >= 100
+		},
+	},
+	{
+		Badge: model.Badge{ID: "total_1000", Emoji: "🏛️", Name: "Thousand Club", Description: "Drop 1000 💩s in total."},
+		unlocked: func(s achievementStats) bool {
+			return s.Total >= 1000
+		},
+	},
+	{
+		Badge: model.Badge{ID: "streak_7", Emoji: "🔥", Name: "Week Warrior", Description: "Keep a 💩 streak for 7 days."},
+		unlocked: func(s achievementStats) bool {
+			return s.LongestPoopStreak.DayCount >= 7
+		},
+	},
+	{
+		Badge: model.Badge{ID: "streak_30", Emoji: "📅", Name: "Like Clockwork", Description: "Keep a 💩 streak for 30 days."},
+		unlocked: func(s achievementStats) bool {
+			return s.LongestPoopStreak.DayCount >= 30
+		},
+	},
+	{
+		Badge: model.Badge{ID: "streak_100", Emoji: "🏆", Name: "Hundred Days", Description: "Keep a 💩 streak for 100 days."},
+		unlocked: func(s achievementStats) bool {
+			return s.LongestPoopStreak.DayCount >= 100
+		},
+	},
+	{
+		Badge: model.Badge{ID: "busy_day_3", Emoji: "🌪️", Name: "Busy Day", Description: "Drop 3 💩s in a single day."},
+		unlocked: func(s achievementStats) bool {
+			return s.MostPoopInADay.Count >= 3
+		},
+	},
+	{
+		Badge: model.Badge{ID: "busy_day_5", Emoji: "🚽", Name: "Marathon", Description: "Drop 5 💩s in a single day."},
+		unlocked: func(s achievementStats) bool {
+			return s.MostPoopInADay.Count >= 5
+		},
+	},
+	{
+		Badge: model.Badge{ID: "comeback", Emoji: "🐫", Name: "Camel", Description: "Come back after going 3 days without 💩."},
+		unlocked: func(s achievementStats) bool {
+			return s.LongestDayWithoutPoop.Duration() >= 72*time.Hour
+		},
+	},
+}
+
+// EvaluateAchievements unlocks every badge whose rule is satisfied and returns
+// the ones that weren't unlocked before.
+func (s *berakService) EvaluateAchievements(ctx context.Context) (_ []model.Achievement, err error) {
+	ctx, end := startSpan(ctx, "berakService.EvaluateAchievements")
+	defer func() { end(err) }()
+
+	var stats achievementStats
+	stats.Total, err = s.repo.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("count poop: %w", err)
+	}
+	stats.LongestPoopStreak, err = s.repo.GetLongestPoopStreak(ctx, s.offset.Load().String())
+	if err != nil {
+		return nil, fmt.Errorf("get longest poop streak: %w", err)
+	}
+	stats.LongestDayWithoutPoop, err = s.repo.GetLongestDayWithoutPoop(ctx, s.offset.Load().String())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get longest day without poop: %w", err)
+	}
+	stats.MostPoopInADay, err = s.repo.GetMostPoopInADay(ctx, s.offset.Load().String())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get most poop in a day: %w", err)
+	}
+
+	var unlocked []model.Achievement
+	for _, b := range badges {
+		if !b.unlocked(stats) {
+			continue
+		}
+		isNew, err := s.repo.UnlockAchievement(ctx, b.ID)
+		if err != nil {
+			return unlocked, fmt.Errorf("unlock achievement[id=%s]: %w", b.ID, err)
+		}
+		if isNew {
+			unlocked = append(unlocked, model.Achievement{Badge: b.Badge, UnlockedAt: s.CurrentTime()})
+		}
+	}
+
+	return unlocked, nil
+}
+
+// GetAchievements returns every badge in the registry, with the unlock time
+// set for the ones that have been unlocked.
+func (s *berakService) GetAchievements(ctx context.Context) (_ []model.Achievement, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetAchievements")
+	defer func() { end(err) }()
+
+	unlockedAt, err := s.repo.GetUnlockedAchievements(ctx, s.offset.Load().String())
+	if err != nil {
+		return nil, fmt.Errorf("get unlocked achievements: %w", err)
+	}
+
+	achievements := make([]model.Achievement, 0, len(badges))
+	for _, b := range badges {
+		achievements = append(achievements, model.Achievement{Badge: b.Badge, UnlockedAt: unlockedAt[b.ID]})
+	}
+
+	return achievements, nil
+}
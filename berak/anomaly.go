@@ -0,0 +1,132 @@
+package berak
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/model"
+)
+
+const (
+	// anomalyWindowDays is the number of days before a day used as its baseline.
+	anomalyWindowDays = 30
+	anomalyZScore     = 3.0
+	// anomalyMinStdDev keeps a very regular baseline from flagging every small
+	// deviation from it (and from dividing by zero).
+	anomalyMinStdDev = 0.5
+	// anomalyGapPercentile is the percentile of all gaps between two 💩s that a
+	// gap has to exceed to be flagged.
+	anomalyGapPercentile = 0.95
+	anomalyMinGaps       = 20
+)
+
+func (s *berakService) GetAnomalies(ctx context.Context) (_ []model.Anomaly, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetAnomalies")
+	defer func() { end(err) }()
+
+	// the gap threshold needs every gap, so the whole log is analysed once
+	// per change, and per day as today's count is judged as it goes.
+	offset, now := s.offset.Load().String(), s.CurrentTime()
+	anomalies, err := s.anomalies.get(s, offset+" "+now.Format(time.DateOnly), func() ([]model.Anomaly, error) {
+		timestamps, err := s.repo.GetTimestamps(ctx, offset)
+		if err != nil {
+			return nil, fmt.Errorf("get timestamps: %w", err)
+		}
+		return detectAnomalies(timestamps, now), nil
+	})
+	// callers may change theirs, e.g. to coarsen it.
+	return slices.Clone(anomalies), err
+}
+
+// detectAnomalies flags days whose count is far off the rolling baseline of the
+// days before it, and days ending a gap longer than most gaps. timestamps must
+// be sorted.
+func detectAnomalies(timestamps []time.Time, now time.Time) []model.Anomaly {
+	if len(timestamps) == 0 {
+		return nil
+	}
+	first := helper.TruncateToDay(timestamps[0])
+	counts := dailyCounts(timestamps, first, now)
+
+	var anomalies []model.Anomaly
+	for i := anomalyWindowDays; i < len(counts); i++ {
+		mean, stdDev := meanStdDev(counts[i-anomalyWindowDays : i])
+		z := (float64(counts[i]) - mean) / max(stdDev, anomalyMinStdDev)
+		a := model.Anomaly{
+			Date:     first.AddDate(0, 0, i),
+			Count:    counts[i],
+			Baseline: round2(mean),
+			ZScore:   round2(z),
+		}
+		switch {
+		case z >= anomalyZScore:
+			a.Kind = model.AnomalyKindHighCount
+		// today isn't over yet, so it can't have too few 💩s.
+		case z <= -anomalyZScore && i < len(counts)-1:
+			a.Kind = model.AnomalyKindLowCount
+		default:
+			continue
+		}
+		anomalies = append(anomalies, a)
+	}
+
+	if len(timestamps)-1 >= anomalyMinGaps {
+		gaps := make([]time.Duration, len(timestamps)-1)
+		for i := range gaps {
+			gaps[i] = timestamps[i+1].Sub(timestamps[i])
+		}
+		sorted := slices.Clone(gaps)
+		slices.Sort(sorted)
+		threshold := sorted[int(math.Ceil(anomalyGapPercentile*float64(len(sorted))))-1]
+		for i, gap := range gaps {
+			if gap <= threshold {
+				continue
+			}
+			date := helper.TruncateToDay(timestamps[i+1])
+			anomalies = append(anomalies, model.Anomaly{
+				Date:  date,
+				Kind:  model.AnomalyKindLongGap,
+				Count: counts[int(date.Sub(first).Hours())/24],
+				Gap: &model.LongestDayWithoutPoop{
+					StartTime: timestamps[i],
+					EndTime:   timestamps[i+1],
+				},
+			})
+		}
+	}
+
+	slices.SortStableFunc(anomalies, func(a, b model.Anomaly) int {
+		return a.Date.Compare(b.Date)
+	})
+	return anomalies
+}
+
+// dailyCounts returns the number of 💩s of every day from first until now.
+func dailyCounts(timestamps []time.Time, first, now time.Time) []int {
+	days := int(helper.TruncateToDay(now).Sub(first).Hours())/24 + 1
+	last := int(helper.TruncateToDay(timestamps[len(timestamps)-1]).Sub(first).Hours())/24 + 1
+	counts := make([]int, max(days, last))
+	for _, t := range timestamps {
+		counts[int(helper.TruncateToDay(t).Sub(first).Hours())/24]++
+	}
+	return counts
+}
+
+func meanStdDev(xs []int) (float64, float64) {
+	var sum, sumSq float64
+	for _, x := range xs {
+		sum += float64(x)
+		sumSq += float64(x) * float64(x)
+	}
+	n := float64(len(xs))
+	mean := sum / n
+	return mean, math.Sqrt(max(sumSq/n-mean*mean, 0))
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,44 @@
+package berak
+
+import "sync"
+
+// cached holds a value computed from the whole log until it changes, so the
+// analyses reading every 💩 don't run again for every page view and every
+// client on every push.
+type cached[T any] struct {
+	mu    sync.Mutex
+	ok    bool
+	key   string
+	value T
+	// changed and swapped are the channels of the service when the value was
+	// computed, either is closed once it's stale.
+	changed, swapped <-chan struct{}
+}
+
+// get returns the value computed for key, computing it again if it's for
+// another key or the log changed since. Concurrent callers wait for a single
+// computation.
+func (c *cached[T]) get(s *berakService, key string, compute func() (T, error)) (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ok && c.key == key && !isClosed(c.changed) && !isClosed(c.swapped) {
+		return c.value, nil
+	}
+	// taken before computing, so a write made meanwhile makes it stale.
+	changed, swapped := s.Changed(), s.Swapped()
+	value, err := compute()
+	if err != nil {
+		return value, err
+	}
+	c.ok, c.key, c.value, c.changed, c.swapped = true, key, value, changed, swapped
+	return value, nil
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -2,6 +2,8 @@ package berak
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,14 +11,16 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/gorilla/mux"
+	"github.com/thansetan/berak/config"
 	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/metrics"
+	"github.com/thansetan/berak/middleware"
 	"github.com/thansetan/berak/model"
 )
 
@@ -24,10 +28,35 @@ type controller struct {
 	tmpl   *template.Template
 	logger *slog.Logger
 	svc    *berakService
+	cfg    *config.Live
+	auth   *middleware.Auth
+	// sseConnections counts the clients following the events.
+	sseConnections *metrics.Gauge
 }
 
-func NewController(svc *berakService, tmpl *template.Template, logger *slog.Logger) *controller {
-	return &controller{tmpl, logger, svc}
+func NewController(svc *berakService, tmpl *template.Template, cfg *config.Live, auth *middleware.Auth, reg *metrics.Registry, logger *slog.Logger) *controller {
+	return &controller{tmpl, logger, svc, cfg, auth, reg.Gauge("berak_sse_connections", "Number of open server-sent events connections.")}
+}
+
+// CheckEvents is a readiness check that the changes can be followed: the
+// database file can be watched, if there's one. It tells how many clients are
+// following them.
+func (c *controller) CheckEvents(ctx context.Context) (string, error) {
+	detail := fmt.Sprintf("%d clients connected", int(c.sseConnections.Value()))
+	path := c.svc.Path()
+	if path == "" {
+		return detail, nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return detail, fmt.Errorf("create watcher: %w", err)
+	}
+	defer watcher.Close()
+	err = watcher.Add(path)
+	if err != nil {
+		return detail, fmt.Errorf("watch sqlite file: %w", err)
+	}
+	return detail, nil
 }
 
 func (c *controller) Event(w http.ResponseWriter, r *http.Request) {
@@ -37,13 +66,22 @@ func (c *controller) Event(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	c.logger.InfoContext(r.Context(), "client connected!", "remote_addr", r.RemoteAddr, "params", r.URL.Query())
+	events := &eventCounter{ResponseWriter: w}
+	w = events
+	connectedAt := time.Now()
+	defer func() {
+		c.logger.InfoContext(r.Context(), "client disconnected!", "remote_addr", r.RemoteAddr, "duration", time.Since(connectedAt), "events", events.n)
+	}()
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", os.Getenv("ALLOWED_SSE_ORIGINS"))
+	w.Header().Set("Access-Control-Allow-Origin", c.cfg.Get().AllowedSSEOrigins)
 	w.Header().Set("Access-Control-Allow-Methods", "GET")
 	w.Header().Set("X-Accel-Buffering", "no")
 
+	c.sseConnections.Inc()
+	defer c.sseConnections.Dec()
+
 	fmt.Fprint(w, "retry:3000\n\n")
 	rc := http.NewResponseController(w)
 	rc.Flush()
@@ -52,26 +90,86 @@ func (c *controller) Event(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		c.logger.ErrorContext(r.Context(), "failed to send poop data!", "error", err, "remote_addr", r.RemoteAddr)
 	}
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		c.logger.ErrorContext(r.Context(), "failed to create watcher!", "error", err, "remote_addr", r.RemoteAddr)
-		helper.OurFault(w)
-		return
-	}
-	defer watcher.Close()
-	err = watcher.Add(os.Getenv("DATA_SOURCE_NAME"))
-	if err != nil {
-		c.logger.ErrorContext(r.Context(), "failed to watch sqlite file!", "error", err, "remote_addr", r.RemoteAddr)
-		helper.OurFault(w)
-		return
+	// watching the database file also catches writes from other processes,
+	// e.g. berak seed, without one only the writes made through the service
+	// are seen. Pushing a write as it happens would tell coarse viewers when
+	// the 💩 dropped, so they're only pushed the day after instead.
+	coarse := isCoarse(r)
+	var (
+		fileEvents <-chan fsnotify.Event
+		changed    <-chan struct{}
+		dayOver    <-chan time.Time
+		version    string
+	)
+	if coarse {
+		dayOver = time.After(c.untilTomorrow())
+	} else if path := c.svc.Path(); path != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			c.logger.ErrorContext(r.Context(), "failed to create watcher!", "error", err, "remote_addr", r.RemoteAddr)
+			helper.OurFault(w)
+			return
+		}
+		defer watcher.Close()
+		err = watcher.Add(path)
+		if err != nil {
+			c.logger.ErrorContext(r.Context(), "failed to watch sqlite file!", "error", err, "remote_addr", r.RemoteAddr)
+			helper.OurFault(w)
+			return
+		}
+		fileEvents = watcher.Events
+		// sessions, tokens and share links are written to the same file, so
+		// a write is only pushed if it changed the 💩s or goals.
+		version, err = c.svc.Version(r.Context())
+		if err != nil {
+			c.logger.ErrorContext(r.Context(), "failed to get data version!", "error", err, "remote_addr", r.RemoteAddr)
+		}
+	} else {
+		changed = c.svc.Changed()
 	}
 
 	keepaliveTicker := time.NewTicker(25 * time.Second)
 	defer keepaliveTicker.Stop()
 
+	seenBadges, err := c.unlockedBadges(r)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get achievements!", "error", err, "remote_addr", r.RemoteAddr)
+	}
+
+	// nor do they get the footer counting up, as it'd stop right after the
+	// 💩 ending the drought.
+	var (
+		droughtTick   <-chan time.Time
+		droughtRecord bool
+	)
+	if !coarse {
+		droughtTicker := time.NewTicker(time.Minute)
+		defer droughtTicker.Stop()
+		droughtTick = droughtTicker.C
+		droughtRecord, err = c.svc.IsOngoingDroughtRecord(r.Context())
+		if err != nil {
+			c.logger.ErrorContext(r.Context(), "failed to check ongoing drought!", "error", err, "remote_addr", r.RemoteAddr)
+		}
+	}
+
+	swapped := c.svc.Swapped()
+	refreshed := c.svc.Refreshed()
+
 	for {
 		select {
-		case event, ok := <-watcher.Events:
+		case <-swapped:
+			// the watcher still watches the old file, so have the client
+			// reload and start over.
+			fmt.Fprint(w, "event:refresh\ndata:\n\n")
+			rc.Flush()
+			c.logger.InfoContext(r.Context(), "database swapped, client told to refresh!", "remote_addr", r.RemoteAddr)
+			return
+		case <-refreshed:
+			fmt.Fprint(w, "event:refresh\ndata:\n\n")
+			rc.Flush()
+			c.logger.InfoContext(r.Context(), "settings changed, client told to refresh!", "remote_addr", r.RemoteAddr)
+			return
+		case event, ok := <-fileEvents:
 			if !ok {
 				c.logger.ErrorContext(r.Context(), "failed to read data from channel!")
 				break
@@ -80,6 +178,34 @@ func (c *controller) Event(w http.ResponseWriter, r *http.Request) {
 				c.logger.InfoContext(r.Context(), "event is not a write event!", "event", event.Name)
 				break
 			}
+			v, err := c.svc.Version(r.Context())
+			if err != nil {
+				c.logger.ErrorContext(r.Context(), "failed to get data version!", "error", err, "remote_addr", r.RemoteAddr)
+				break
+			}
+			if v == version {
+				break
+			}
+			version = v
+			c.sendUpdate(w, r, period, seenBadges)
+		case <-changed:
+			changed = c.svc.Changed()
+			c.sendUpdate(w, r, period, seenBadges)
+		case <-dayOver:
+			dayOver = time.After(c.untilTomorrow())
+			c.sendUpdate(w, r, period, seenBadges)
+		case <-droughtTick:
+			isRecord, err := c.svc.IsOngoingDroughtRecord(r.Context())
+			if err != nil {
+				c.logger.ErrorContext(r.Context(), "failed to check ongoing drought!", "error", err, "remote_addr", r.RemoteAddr)
+				break
+			}
+			// keep pushing while the ongoing drought holds the record so the
+			// footer keeps counting up, and once more when it stops holding it.
+			if !isRecord && !droughtRecord {
+				break
+			}
+			droughtRecord = isRecord
 			err = c.sendPoopData(w, r, period)
 			if err != nil {
 				c.logger.ErrorContext(r.Context(), "failed to send poop data!", "error", err, "remote_addr", r.RemoteAddr)
@@ -96,12 +222,50 @@ func (c *controller) Event(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		case <-r.Context().Done():
-			c.logger.InfoContext(r.Context(), "client disconnected!", "remote_addr", r.RemoteAddr)
 			return
 		}
 	}
 }
 
+// untilTomorrow returns how long until the day is over in the log's time zone.
+func (c *controller) untilTomorrow() time.Duration {
+	now := c.svc.CurrentTime()
+	return helper.TruncateToDay(now).AddDate(0, 0, 1).Sub(now)
+}
+
+// eventCounter counts the server-sent events written through it, each of
+// which starts with a write of its event line.
+type eventCounter struct {
+	http.ResponseWriter
+	n int
+}
+
+func (ec *eventCounter) Write(b []byte) (int, error) {
+	if bytes.HasPrefix(b, []byte("event:")) {
+		ec.n++
+	}
+	return ec.ResponseWriter.Write(b)
+}
+
+func (ec *eventCounter) Unwrap() http.ResponseWriter {
+	return ec.ResponseWriter
+}
+
+// sendUpdate sends the new poop data and any badges unlocked since seen.
+func (c *controller) sendUpdate(w http.ResponseWriter, r *http.Request, period string, seen map[string]bool) {
+	err := c.sendPoopData(w, r, period)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to send poop data!", "error", err, "remote_addr", r.RemoteAddr)
+	}
+	if isShared(r) {
+		return
+	}
+	err = c.sendUnlockedBadges(w, r, seen)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to send unlocked badges!", "error", err, "remote_addr", r.RemoteAddr)
+	}
+}
+
 func (c *controller) sendPoopData(w http.ResponseWriter, r *http.Request, period string) error {
 	now := c.svc.CurrentTime()
 	yearStr := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("year")))
@@ -137,10 +301,11 @@ func (c *controller) sendPoopData(w http.ResponseWriter, r *http.Request, period
 			fetchTable = false
 			break
 		}
-		tableData, err = c.svc.GetDaily(r.Context(), now, year, month)
+		tableData, err = c.getDaily(r, now, year, month)
 		if err != nil {
 			return fmt.Errorf("error getting daily data: %w", err)
 		}
+		tableData = sharedDays(r, year, month, tableData)
 		templateName = "daily_table"
 	}
 	m := make(map[string]string)
@@ -155,7 +320,12 @@ func (c *controller) sendPoopData(w http.ResponseWriter, r *http.Request, period
 		buf.Reset()
 	}
 
-	stats, err := c.svc.GetStatistics(r.Context())
+	// a share link only shares its days, not the statistics of the whole log.
+	if isShared(r) {
+		return c.writePoopUpdate(w, m)
+	}
+
+	stats, err := c.getStatistics(r)
 	if err != nil {
 		return fmt.Errorf("error getting statistics: %w", err)
 	}
@@ -176,6 +346,10 @@ func (c *controller) sendPoopData(w http.ResponseWriter, r *http.Request, period
 	}
 	m["poop-current"] = buf.String()
 
+	return c.writePoopUpdate(w, m)
+}
+
+func (c *controller) writePoopUpdate(w http.ResponseWriter, m map[string]string) error {
 	fmt.Fprint(w, "event:poopupdate\n")
 	jsonBytes, err := json.Marshal(m)
 	if err != nil {
@@ -190,6 +364,128 @@ func (c *controller) sendPoopData(w http.ResponseWriter, r *http.Request, period
 	return nil
 }
 
+func (c *controller) unlockedBadges(r *http.Request) (map[string]bool, error) {
+	achievements, err := c.getAchievements(r)
+	if err != nil {
+		return nil, err
+	}
+	unlocked := make(map[string]bool)
+	for _, a := range achievements {
+		if a.IsUnlocked() {
+			unlocked[a.ID] = true
+		}
+	}
+	return unlocked, nil
+}
+
+// sendUnlockedBadges announces badges that were unlocked since the client
+// connected, marking them as seen so they're only announced once.
+func (c *controller) sendUnlockedBadges(w http.ResponseWriter, r *http.Request, seen map[string]bool) error {
+	if seen == nil {
+		return nil
+	}
+	achievements, err := c.getAchievements(r)
+	if err != nil {
+		return fmt.Errorf("error getting achievements: %w", err)
+	}
+	var unlocked []model.Achievement
+	for _, a := range achievements {
+		if a.IsUnlocked() && !seen[a.ID] {
+			seen[a.ID] = true
+			unlocked = append(unlocked, a)
+		}
+	}
+	if len(unlocked) == 0 {
+		return nil
+	}
+
+	jsonBytes, err := json.Marshal(unlocked)
+	if err != nil {
+		return fmt.Errorf("error marshalling JSON: %w", err)
+	}
+	fmt.Fprintf(w, "event:badgeunlocked\ndata:%s\n\n", string(jsonBytes))
+	rc := http.NewResponseController(w)
+	err = rc.Flush()
+	if err != nil {
+		return fmt.Errorf("error flushing writer: %w", err)
+	}
+	return nil
+}
+
+// sharedDays drops the days of a month that the viewer's share link, if any,
+// doesn't share.
+func sharedDays(r *http.Request, year, month uint64, tableData model.TableData) model.TableData {
+	l, ok := middleware.ShareFromContext(r.Context())
+	if !ok {
+		return tableData
+	}
+	data := make([]model.AggData, 0, len(tableData.Data))
+	for _, d := range tableData.Data {
+		day := time.Date(int(year), time.Month(month), d.Period, 0, 0, 0, 0, time.UTC)
+		if l.CoversRange(day, day) {
+			data = append(data, d)
+		}
+	}
+	tableData.Data = data
+	return tableData
+}
+
+func isShared(r *http.Request) bool {
+	_, ok := middleware.ShareFromContext(r.Context())
+	return ok
+}
+
+// isCoarse reports whether the viewer of r may only see dates, not times.
+func isCoarse(r *http.Request) bool {
+	return middleware.VisibilityFromContext(r.Context()) == model.VisibilityCoarse
+}
+
+func (c *controller) getStatistics(r *http.Request) (model.Statistics, error) {
+	stats, err := c.svc.GetStatistics(r.Context())
+	if err != nil {
+		return stats, err
+	}
+	// the goals are only shown to those who may read them through the API.
+	canReadGoals, err := c.auth.HasScope(r, model.ScopeExport)
+	if err != nil {
+		return stats, fmt.Errorf("check scope: %w", err)
+	}
+	if !canReadGoals {
+		stats.Goals = nil
+	}
+	if isCoarse(r) {
+		return stats.Coarse(), nil
+	}
+	return stats, nil
+}
+
+func (c *controller) getDaily(r *http.Request, now time.Time, year, month uint64) (model.TableData, error) {
+	tableData, err := c.svc.GetDaily(r.Context(), now, year, month)
+	if err != nil || !isCoarse(r) {
+		return tableData, err
+	}
+	return tableData.Coarse(), nil
+}
+
+func (c *controller) getAchievements(r *http.Request) ([]model.Achievement, error) {
+	achievements, err := c.svc.GetAchievements(r.Context())
+	if err != nil || !isCoarse(r) {
+		return achievements, err
+	}
+	for i := range achievements {
+		achievements[i] = achievements[i].Coarse()
+	}
+	return achievements, nil
+}
+
+func (c *controller) getRecords(r *http.Request, n int) (model.Records, error) {
+	records, err := c.svc.GetRecords(r.Context(), n)
+	if err != nil || !isCoarse(r) {
+		return records, err
+	}
+	return records.Coarse(), nil
+}
+
 func (c *controller) Create(w http.ResponseWriter, r *http.Request) {
 	var data struct {
 		Timestamp time.Time `json:"timestamp"`
@@ -222,13 +518,18 @@ func (c *controller) Create(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	err = c.svc.Add(r.Context(), data.Timestamp.UTC())
-	if err != nil {
+	unlocked, err := c.svc.Add(r.Context(), data.Timestamp.UTC())
+	if errors.Is(err, ErrEvaluateAchievements) {
+		c.logger.ErrorContext(r.Context(), "failed to evaluate achievements", "error", err.Error(), "remote_addr", r.RemoteAddr)
+	} else if err != nil {
 		c.logger.ErrorContext(r.Context(), "failed to add new 💩", "error", err.Error(), "remote_addr", r.RemoteAddr)
 		helper.OurFault(w)
 		return
 	}
 	c.logger.InfoContext(r.Context(), "new 💩 added!", "remote_addr", r.RemoteAddr)
+	for _, a := range unlocked {
+		c.logger.InfoContext(r.Context(), "achievement unlocked!", "badge", a.ID)
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -264,7 +565,7 @@ func (c *controller) GetMonthly(w http.ResponseWriter, r *http.Request) {
 		helper.OurFault(w)
 		return
 	}
-	stats, err := c.svc.GetStatistics(r.Context())
+	stats, err := c.getStatistics(r)
 	if err != nil {
 		c.logger.ErrorContext(r.Context(), "failed to get statistics data!", "error", err)
 		helper.OurFault(w)
@@ -276,7 +577,7 @@ func (c *controller) GetMonthly(w http.ResponseWriter, r *http.Request) {
 		Year:       int(year),
 		TableData:  tableData,
 		Statistics: stats,
-		BaseURL:    os.Getenv("BASE_URL"),
+		BaseURL:    c.cfg.Get().BaseURL,
 	})
 	if err != nil {
 		c.logger.ErrorContext(r.Context(), "failed to execute year template", "error", err.Error(), "remote_addr", r.RemoteAddr)
@@ -306,32 +607,68 @@ func (c *controller) GetDaily(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tableData, err := c.svc.GetDaily(r.Context(), now, year, month)
+	tableData, err := c.getDaily(r, now, year, month)
 	if err != nil {
 		c.logger.ErrorContext(r.Context(), "failed to get daily data!", "error", err)
 		helper.OurFault(w)
 		return
 	}
-	stats, err := c.svc.GetStatistics(r.Context())
-	if err != nil {
-		c.logger.ErrorContext(r.Context(), "failed to get statistics data!", "error", err)
-		helper.OurFault(w)
-		return
+	tableData = sharedDays(r, year, month, tableData)
+
+	var stats model.Statistics
+	if !isShared(r) {
+		stats, err = c.getStatistics(r)
+		if err != nil {
+			c.logger.ErrorContext(r.Context(), "failed to get statistics data!", "error", err)
+			helper.OurFault(w)
+			return
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
+	sess, _ := middleware.SessionFromContext(r.Context())
 	err = c.tmpl.ExecuteTemplate(w, "month", model.Data{
 		Year:       int(year),
 		Month:      int(month),
 		TableData:  tableData,
 		Statistics: stats,
-		BaseURL:    os.Getenv("BASE_URL"),
+		Session:    sess,
+		Shared:     isShared(r),
+		BaseURL:    c.cfg.Get().BaseURL,
 	})
 	if err != nil {
 		c.logger.ErrorContext(r.Context(), "failed to execute month template", "error", err.Error(), "remote_addr", r.RemoteAddr)
 	}
 }
 
+func (c *controller) GetAll(w http.ResponseWriter, r *http.Request) {
+	now := c.svc.CurrentTime()
+	years, err := c.svc.GetYearlySummaries(r.Context(), now)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get yearly summaries!", "error", err)
+		helper.OurFault(w)
+		return
+	}
+	stats, err := c.getStatistics(r)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get statistics data!", "error", err)
+		helper.OurFault(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err = c.tmpl.ExecuteTemplate(w, "all", model.Data{
+		Year:       now.Year(),
+		TableData:  model.TableData{CurrentTime: now},
+		Statistics: stats,
+		Years:      years,
+		BaseURL:    c.cfg.Get().BaseURL,
+	})
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to execute all template", "error", err.Error(), "remote_addr", r.RemoteAddr)
+	}
+}
+
 func (c *controller) GetLastPoopTime(w http.ResponseWriter, r *http.Request) {
 	lastPoopTime, err := c.svc.GetLastPoopTime(r.Context())
 	if err != nil {
@@ -339,6 +676,9 @@ func (c *controller) GetLastPoopTime(w http.ResponseWriter, r *http.Request) {
 		helper.OurFault(w)
 		return
 	}
+	if isCoarse(r) {
+		lastPoopTime = helper.TruncateToDay(lastPoopTime)
+	}
 	helper.WriteJSON(w, http.StatusOK, struct {
 		LastPoopTime time.Time `json:"last_poop_time"`
 	}{
@@ -346,17 +686,239 @@ func (c *controller) GetLastPoopTime(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (c *controller) GetSQLiteFile(w http.ResponseWriter, r *http.Request) {
-	filePath := os.Getenv("DATA_SOURCE_NAME")
-	_, err := os.Stat(filePath)
+func (c *controller) GetBadges(w http.ResponseWriter, r *http.Request) {
+	achievements, err := c.getAchievements(r)
 	if err != nil {
-		c.logger.ErrorContext(r.Context(), "file doesn't exist", "error", err)
+		c.logger.ErrorContext(r.Context(), "failed to get achievements!", "error", err)
 		helper.OurFault(w)
 		return
 	}
-	w.Header().Set("Content-Disposition", "attachment; filename=berak.sqlite3")
-	w.Header().Set("Content-Type", "application/octet-stream")
-	http.ServeFile(w, r, filePath)
+	stats, err := c.getStatistics(r)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get statistics data!", "error", err)
+		helper.OurFault(w)
+		return
+	}
+
+	now := c.svc.CurrentTime()
+	w.WriteHeader(http.StatusOK)
+	err = c.tmpl.ExecuteTemplate(w, "badges", model.Data{
+		Year:         now.Year(),
+		TableData:    model.TableData{CurrentTime: now},
+		Statistics:   stats,
+		Achievements: achievements,
+		BaseURL:      c.cfg.Get().BaseURL,
+	})
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to execute badges template", "error", err.Error(), "remote_addr", r.RemoteAddr)
+	}
+}
+
+const (
+	defaultRecordsLimit = 10
+	maxRecordsLimit     = 100
+)
+
+func (c *controller) GetRecords(w http.ResponseWriter, r *http.Request) {
+	records, err := c.getRecords(r, defaultRecordsLimit)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get records!", "error", err)
+		helper.OurFault(w)
+		return
+	}
+	stats, err := c.getStatistics(r)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get statistics data!", "error", err)
+		helper.OurFault(w)
+		return
+	}
+
+	now := c.svc.CurrentTime()
+	w.WriteHeader(http.StatusOK)
+	err = c.tmpl.ExecuteTemplate(w, "records", model.Data{
+		Year:       now.Year(),
+		TableData:  model.TableData{CurrentTime: now},
+		Statistics: stats,
+		Records:    records,
+		BaseURL:    c.cfg.Get().BaseURL,
+	})
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to execute records template", "error", err.Error(), "remote_addr", r.RemoteAddr)
+	}
+}
+
+func (c *controller) GetRecordsJSON(w http.ResponseWriter, r *http.Request) {
+	n := defaultRecordsLimit
+	if nStr := strings.TrimSpace(r.URL.Query().Get("n")); nStr != "" {
+		parsed, err := strconv.Atoi(nStr)
+		if err != nil || parsed < 1 || parsed > maxRecordsLimit {
+			helper.WriteMessage(w, http.StatusBadRequest, fmt.Sprintf("n must be a number between 1 and %d!", maxRecordsLimit))
+			return
+		}
+		n = parsed
+	}
+
+	records, err := c.getRecords(r, n)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get records", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, records)
+}
+
+func (c *controller) GetStatisticsJSON(w http.ResponseWriter, r *http.Request) {
+	stats, err := c.getStatistics(r)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get statistics data", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, struct {
+		model.Statistics
+		CurrentTime time.Time `json:"current_time"`
+	}{
+		Statistics:  stats,
+		CurrentTime: c.svc.CurrentTime(),
+	})
+}
+
+// GetDailyJSON returns the 💩 count of every day of a month, until today for
+// the current month.
+func (c *controller) GetDailyJSON(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	now := c.svc.CurrentTime()
+	year, err := strconv.ParseUint(vars["year"], 10, 64)
+	if err != nil || (year < 1 || year > uint64(now.Year())) {
+		helper.WriteMessage(w, http.StatusNotFound, "year not found!")
+		return
+	}
+	month, err := strconv.ParseUint(vars["month"], 10, 8)
+	if err != nil || (month < 1 || month > 12) || (year == uint64(now.Year()) && month > uint64(now.Month())) {
+		helper.WriteMessage(w, http.StatusNotFound, "month not found!")
+		return
+	}
+
+	tableData, err := c.getDaily(r, now, year, month)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get daily data", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+
+	type day struct {
+		Day   int `json:"day"`
+		Count int `json:"count"`
+	}
+	days := make([]day, 0, len(tableData.Data))
+	var total int
+	for _, d := range tableData.Data {
+		days = append(days, day{Day: d.Period, Count: d.Count})
+		total += d.Count
+	}
+	helper.WriteJSON(w, http.StatusOK, struct {
+		Year  int   `json:"year"`
+		Month int   `json:"month"`
+		Total int   `json:"total"`
+		Days  []day `json:"days"`
+	}{
+		Year:  int(year),
+		Month: int(month),
+		Total: total,
+		Days:  days,
+	})
+}
+
+func (c *controller) GetAnomalies(w http.ResponseWriter, r *http.Request) {
+	anomalies, err := c.svc.GetAnomalies(r.Context())
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get anomalies", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	if anomalies == nil {
+		anomalies = []model.Anomaly{}
+	}
+	if isCoarse(r) {
+		for i := range anomalies {
+			anomalies[i] = anomalies[i].Coarse()
+		}
+	}
+	helper.WriteJSON(w, http.StatusOK, anomalies)
+}
+
+func (c *controller) GetForecast(w http.ResponseWriter, r *http.Request) {
+	if isCoarse(r) {
+		helper.WriteMessage(w, http.StatusNotFound, "the forecast isn't public!")
+		return
+	}
+	forecast, err := c.svc.GetForecast(r.Context())
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get forecast", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	if forecast.IsEmpty() {
+		helper.WriteMessage(w, http.StatusNotFound, "not enough 💩s to forecast the next one!")
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, forecast)
+}
+
+// Export returns the 💩s between the from and to dates as CSV, or as JSON with
+// format=json.
+func (c *controller) Export(w http.ResponseWriter, r *http.Request) {
+	var (
+		q        = r.URL.Query()
+		from, to time.Time
+		err      error
+	)
+	if fromStr := q.Get("from"); fromStr != "" {
+		from, err = time.Parse(time.DateOnly, fromStr)
+		if err != nil {
+			helper.WriteMessage(w, http.StatusBadRequest, "from must be a date formatted as YYYY-MM-DD!")
+			return
+		}
+	}
+	if toStr := q.Get("to"); toStr != "" {
+		to, err = time.Parse(time.DateOnly, toStr)
+		if err != nil {
+			helper.WriteMessage(w, http.StatusBadRequest, "to must be a date formatted as YYYY-MM-DD!")
+			return
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		helper.WriteMessage(w, http.StatusBadRequest, "to can't be before from!")
+		return
+	}
+
+	timestamps, err := c.svc.GetTimestampsBetween(r.Context(), from, to)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get timestamps", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+
+	formatted := make([]string, len(timestamps))
+	for i, t := range timestamps {
+		formatted[i] = t.Format(time.DateTime)
+	}
+	if q.Get("format") == "json" {
+		helper.WriteJSON(w, http.StatusOK, formatted)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=berak.csv")
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"timestamp"})
+	for _, t := range formatted {
+		cw.Write([]string{t})
+	}
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to write csv", "error", err.Error(), "remote_addr", r.RemoteAddr)
+	}
 }
 
 func (c controller) FourOFour(w http.ResponseWriter, r *http.Request) {
@@ -366,3 +928,113 @@ func (c controller) FourOFour(w http.ResponseWriter, r *http.Request) {
 		c.logger.ErrorContext(r.Context(), "failed to execute 404 template", "error", err.Error(), "remote_addr", r.RemoteAddr)
 	}
 }
+
+type goalRequest struct {
+	Kind   model.GoalKind `json:"kind"`
+	Target int            `json:"target"`
+}
+
+func (c *controller) GetGoals(w http.ResponseWriter, r *http.Request) {
+	goals, err := c.svc.GetGoalsProgress(r.Context())
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get goals", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	if goals == nil {
+		goals = []model.GoalProgress{}
+	}
+	helper.WriteJSON(w, http.StatusOK, goals)
+}
+
+func (c *controller) GetGoal(w http.ResponseWriter, r *http.Request) {
+	id, ok := c.goalID(w, r)
+	if !ok {
+		return
+	}
+	goal, err := c.svc.GetGoalProgress(r.Context(), id)
+	if err != nil {
+		c.writeGoalError(w, r, "failed to get goal", err)
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, goal)
+}
+
+func (c *controller) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	req, ok := c.decodeGoal(w, r)
+	if !ok {
+		return
+	}
+	goal, err := c.svc.CreateGoal(r.Context(), req.Kind, req.Target)
+	if err != nil {
+		c.writeGoalError(w, r, "failed to create goal", err)
+		return
+	}
+	c.logger.InfoContext(r.Context(), "new goal added!", "id", goal.ID, "remote_addr", r.RemoteAddr)
+	helper.WriteJSON(w, http.StatusCreated, goal)
+}
+
+func (c *controller) UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	id, ok := c.goalID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := c.decodeGoal(w, r)
+	if !ok {
+		return
+	}
+	goal, err := c.svc.UpdateGoal(r.Context(), id, req.Kind, req.Target)
+	if err != nil {
+		c.writeGoalError(w, r, "failed to update goal", err)
+		return
+	}
+	c.logger.InfoContext(r.Context(), "goal updated!", "id", goal.ID, "remote_addr", r.RemoteAddr)
+	helper.WriteJSON(w, http.StatusOK, goal)
+}
+
+func (c *controller) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	id, ok := c.goalID(w, r)
+	if !ok {
+		return
+	}
+	err := c.svc.DeleteGoal(r.Context(), id)
+	if err != nil {
+		c.writeGoalError(w, r, "failed to delete goal", err)
+		return
+	}
+	c.logger.InfoContext(r.Context(), "goal removed!", "id", id, "remote_addr", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *controller) goalID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		helper.WriteMessage(w, http.StatusNotFound, "goal not found!")
+		return 0, false
+	}
+	return id, true
+}
+
+func (c *controller) decodeGoal(w http.ResponseWriter, r *http.Request) (goalRequest, bool) {
+	var req goalRequest
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		c.logger.InfoContext(r.Context(), "failed to decode goal", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.WriteMessage(w, http.StatusBadRequest, "invalid JSON format!")
+		return req, false
+	}
+	return req, true
+}
+
+func (c *controller) writeGoalError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	switch {
+	case errors.Is(err, ErrGoalNotFound):
+		helper.WriteMessage(w, http.StatusNotFound, "goal not found!")
+	case errors.Is(err, ErrInvalidGoal):
+		helper.WriteMessage(w, http.StatusBadRequest, err.Error())
+	default:
+		c.logger.ErrorContext(r.Context(), msg, "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+	}
+}
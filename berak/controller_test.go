@@ -0,0 +1,167 @@
+package berak
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thansetan/berak/config"
+	"github.com/thansetan/berak/metrics"
+	"github.com/thansetan/berak/middleware"
+	"github.com/thansetan/berak/model"
+)
+
+// timeOfDay matches what gives away when a 💩 dropped: a clock time, or a
+// length precise to the hour or minute.
+var timeOfDay = regexp.MustCompile(`\d{1,2}:\d{2}|hour|minute`)
+
+func TestDailyTableCoarse(t *testing.T) {
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"add": func(a, b int) int { return a + b },
+		"tai": func(n int) string { return strings.Repeat("💩", n) },
+	}).ParseFiles("../templates/components/daily-table.html")
+	if err != nil {
+		t.Fatalf("parse template: %v", err)
+	}
+
+	// a long gap ending a minute ago, after a month of a 💩 a day.
+	last := time.Now().UTC().Add(7 * time.Hour).Truncate(time.Minute).Add(-time.Minute)
+	gapStart := last.Add(-(3*24*time.Hour + 5*time.Hour + 17*time.Minute))
+	var poops []time.Time
+	for i := 30; i >= 0; i-- {
+		poops = append(poops, gapStart.AddDate(0, 0, -i))
+	}
+	svc, _ := newTestService(t, append(poops, last)...)
+	now := svc.CurrentTime()
+
+	render := func(coarse bool) string {
+		t.Helper()
+		data, err := svc.GetDaily(context.Background(), now, uint64(now.Year()), uint64(now.Month()))
+		if err != nil {
+			t.Fatalf("GetDaily: %v", err)
+		}
+		if coarse {
+			data = data.Coarse()
+		}
+		var buf bytes.Buffer
+		err = tmpl.ExecuteTemplate(&buf, "daily_table", data)
+		if err != nil {
+			t.Fatalf("execute template: %v", err)
+		}
+		titles := regexp.MustCompile(`title="([^"]*)"`).FindAllStringSubmatch(buf.String(), -1)
+		if len(titles) == 0 {
+			t.Fatal("no anomaly tooltip rendered")
+		}
+		var all []string
+		for _, title := range titles {
+			all = append(all, title[1])
+		}
+		return strings.Join(all, "\n")
+	}
+
+	if exact := render(false); !timeOfDay.MatchString(exact) {
+		t.Fatalf("tooltip %q doesn't tell the length of the gap", exact)
+	}
+	if coarse := render(true); timeOfDay.MatchString(coarse) {
+		t.Errorf("coarse tooltip %q gives away the time of day", coarse)
+	}
+}
+
+// newTestController returns a controller of svc rendering the templates of
+// the app.
+func newTestController(t *testing.T, svc *berakService) *controller {
+	t.Helper()
+	tmpl := template.New("").Funcs(template.FuncMap{
+		"add":            func(a, b int) int { return a + b },
+		"getMonthName":   func(monthNumber int) string { return time.Month(monthNumber).String() },
+		"formatDateTime": func(t time.Time, coarse bool) string { return t.String() },
+		"tai":            func(n int) string { return strings.Repeat("💩", n) },
+	})
+	for _, pattern := range []string{"../templates/*.html", "../templates/components/*.html"} {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatalf("glob templates: %v", err)
+		}
+		tmpl, err = tmpl.ParseFiles(files...)
+		if err != nil {
+			t.Fatalf("parse templates: %v", err)
+		}
+	}
+	return NewController(svc, tmpl, config.NewLive("", config.Config{}), middleware.NewAuth(nil, nil), metrics.NewRegistry(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestEventCoarse(t *testing.T) {
+	tests := []struct {
+		visibility model.Visibility
+		wantPush   bool
+	}{
+		{model.VisibilityPublic, true},
+		// a push would tell when the 💩 dropped.
+		{model.VisibilityCoarse, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.visibility), func(t *testing.T) {
+			svc, _ := newTestService(t, daysAgo(1, 8))
+			c := newTestController(t, svc)
+			srv := httptest.NewServer(middleware.NewPrivacy(tt.visibility, c.auth, nil).Handle(http.HandlerFunc(c.Event)))
+			defer srv.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/sse?period=monthly&year=%d", srv.URL, svc.CurrentTime().Year()), nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			defer res.Body.Close()
+			events := make(chan string, 10)
+			go func() {
+				defer close(events)
+				scanner := bufio.NewScanner(res.Body)
+				scanner.Buffer(nil, 1<<20)
+				for scanner.Scan() {
+					if event, ok := strings.CutPrefix(scanner.Text(), "event:"); ok {
+						events <- event
+					}
+				}
+			}()
+
+			select {
+			case event := <-events:
+				if event != "poopupdate" {
+					t.Fatalf("first event = %q, want the current data", event)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the current data wasn't sent")
+			}
+			_, err = svc.Add(context.Background(), time.Time{})
+			if err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+			select {
+			case event := <-events:
+				if !tt.wantPush {
+					t.Errorf("got event %q within the same day", event)
+				}
+			case <-time.After(500 * time.Millisecond):
+				if tt.wantPush {
+					t.Error("the 💩 wasn't pushed")
+				}
+			}
+		})
+	}
+}
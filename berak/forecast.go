@@ -0,0 +1,71 @@
+package berak
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/thansetan/berak/model"
+)
+
+// forecastMinSamples is the number of past intervals starting at the same hour
+// of day as the last 💩 needed to use them instead of every interval.
+const forecastMinSamples = 5
+
+func (s *berakService) GetForecast(ctx context.Context) (_ model.Forecast, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetForecast")
+	defer func() { end(err) }()
+
+	// it only changes with the log, not with every page or client asking.
+	offset := s.offset.Load().String()
+	return s.forecast.get(s, offset, func() (model.Forecast, error) {
+		timestamps, err := s.repo.GetTimestamps(ctx, offset)
+		if err != nil {
+			return model.Forecast{}, fmt.Errorf("get timestamps: %w", err)
+		}
+		return forecastNextPoop(timestamps), nil
+	})
+}
+
+// forecastNextPoop predicts when the next 💩 will drop using the intervals
+// that followed past 💩s dropped at the same hour of day as the last one: the
+// median interval gives the expected time and the interquartile range gives
+// the window around it. timestamps must be sorted.
+func forecastNextPoop(timestamps []time.Time) model.Forecast {
+	if len(timestamps) < 2 {
+		return model.Forecast{}
+	}
+	last := timestamps[len(timestamps)-1]
+
+	var all, sameHour []time.Duration
+	for i := 1; i < len(timestamps); i++ {
+		interval := timestamps[i].Sub(timestamps[i-1])
+		all = append(all, interval)
+		if timestamps[i-1].Hour() == last.Hour() {
+			sameHour = append(sameHour, interval)
+		}
+	}
+	intervals := all
+	if len(sameHour) >= forecastMinSamples {
+		intervals = sameHour
+	}
+	slices.Sort(intervals)
+
+	return model.Forecast{
+		Expected:   last.Add(quantile(intervals, 0.5)),
+		From:       last.Add(quantile(intervals, 0.25)),
+		To:         last.Add(quantile(intervals, 0.75)),
+		SampleSize: len(intervals),
+	}
+}
+
+// quantile returns the q-th quantile of sorted using linear interpolation.
+func quantile(sorted []time.Duration, q float64) time.Duration {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + time.Duration((pos-float64(i))*float64(sorted[i+1]-sorted[i]))
+}
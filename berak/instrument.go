@@ -0,0 +1,234 @@
+package berak
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/thansetan/berak/metrics"
+	"github.com/thansetan/berak/model"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentedRepository times and traces every query of the Repository it
+// wraps.
+type instrumentedRepository struct {
+	Repository
+	duration *metrics.Histogram
+}
+
+// NewInstrumentedRepo returns repo recording the duration of each of its
+// methods in reg, and a span for each call.
+func NewInstrumentedRepo(repo Repository, reg *metrics.Registry) Repository {
+	return &instrumentedRepository{
+		Repository: repo,
+		duration:   reg.Histogram("berak_db_query_duration_seconds", "Duration of database queries by repository method.", metrics.DefBuckets, "method"),
+	}
+}
+
+// start starts timing method and its span, which end calls with the error
+// it returned. Calls outside of a trace, like those of the metrics collector,
+// don't get a span, as they'd each make a trace of their own.
+func (r *instrumentedRepository) start(ctx context.Context, method string) (context.Context, func(err error)) {
+	t0 := time.Now()
+	span := trace.SpanFromContext(ctx)
+	if span.SpanContext().IsValid() {
+		ctx, span = tracer.Start(ctx, "Repository."+method)
+	}
+	return ctx, func(err error) {
+		r.duration.ObserveSince(t0, method)
+		if !span.IsRecording() {
+			return
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (r *instrumentedRepository) Add(ctx context.Context) (err error) {
+	ctx, end := r.start(ctx, "Add")
+	defer func() { end(err) }()
+	return r.Repository.Add(ctx)
+}
+
+func (r *instrumentedRepository) AddWithDate(ctx context.Context, t time.Time) (err error) {
+	ctx, end := r.start(ctx, "AddWithDate")
+	defer func() { end(err) }()
+	return r.Repository.AddWithDate(ctx, t)
+}
+
+func (r *instrumentedRepository) DeleteLast(ctx context.Context) (err error) {
+	ctx, end := r.start(ctx, "DeleteLast")
+	defer func() { end(err) }()
+	return r.Repository.DeleteLast(ctx)
+}
+
+func (r *instrumentedRepository) Count(ctx context.Context) (_ int, err error) {
+	ctx, end := r.start(ctx, "Count")
+	defer func() { end(err) }()
+	return r.Repository.Count(ctx)
+}
+
+func (r *instrumentedRepository) GetMonthlyByYear(ctx context.Context, year uint64, offset string) (_ []model.AggData, err error) {
+	ctx, end := r.start(ctx, "GetMonthlyByYear")
+	defer func() { end(err) }()
+	return r.Repository.GetMonthlyByYear(ctx, year, offset)
+}
+
+func (r *instrumentedRepository) GetDailyByMonthAndYear(ctx context.Context, year, month uint64, offset string) (_ []model.AggData, err error) {
+	ctx, end := r.start(ctx, "GetDailyByMonthAndYear")
+	defer func() { end(err) }()
+	return r.Repository.GetDailyByMonthAndYear(ctx, year, month, offset)
+}
+
+func (r *instrumentedRepository) GetLastDataTimestamp(ctx context.Context, offset string) (_ time.Time, err error) {
+	ctx, end := r.start(ctx, "GetLastDataTimestamp")
+	defer func() { end(err) }()
+	return r.Repository.GetLastDataTimestamp(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetTimestamps(ctx context.Context, offset string) (_ []time.Time, err error) {
+	ctx, end := r.start(ctx, "GetTimestamps")
+	defer func() { end(err) }()
+	return r.Repository.GetTimestamps(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetYearlySummaries(ctx context.Context, offset string) (_ []model.YearSummary, err error) {
+	ctx, end := r.start(ctx, "GetYearlySummaries")
+	defer func() { end(err) }()
+	return r.Repository.GetYearlySummaries(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetLongestDayWithoutPoop(ctx context.Context, offset string) (_ model.LongestDayWithoutPoop, err error) {
+	ctx, end := r.start(ctx, "GetLongestDayWithoutPoop")
+	defer func() { end(err) }()
+	return r.Repository.GetLongestDayWithoutPoop(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetLongestDaysWithoutPoop(ctx context.Context, offset string, n int) (_ []model.LongestDayWithoutPoop, err error) {
+	ctx, end := r.start(ctx, "GetLongestDaysWithoutPoop")
+	defer func() { end(err) }()
+	return r.Repository.GetLongestDaysWithoutPoop(ctx, offset, n)
+}
+
+func (r *instrumentedRepository) GetMostPoopInADay(ctx context.Context, offset string) (_ model.MostPoopInADate, err error) {
+	ctx, end := r.start(ctx, "GetMostPoopInADay")
+	defer func() { end(err) }()
+	return r.Repository.GetMostPoopInADay(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetDaysWithMostPoop(ctx context.Context, offset string, n int) (_ []model.MostPoopInADate, err error) {
+	ctx, end := r.start(ctx, "GetDaysWithMostPoop")
+	defer func() { end(err) }()
+	return r.Repository.GetDaysWithMostPoop(ctx, offset, n)
+}
+
+func (r *instrumentedRepository) GetLongestPoopStreak(ctx context.Context, offset string) (_ model.PoopStreak, err error) {
+	ctx, end := r.start(ctx, "GetLongestPoopStreak")
+	defer func() { end(err) }()
+	return r.Repository.GetLongestPoopStreak(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetLongestPoopStreaks(ctx context.Context, offset string, n int) (_ []model.PoopStreak, err error) {
+	ctx, end := r.start(ctx, "GetLongestPoopStreaks")
+	defer func() { end(err) }()
+	return r.Repository.GetLongestPoopStreaks(ctx, offset, n)
+}
+
+func (r *instrumentedRepository) GetLongestPoopStreakPerYear(ctx context.Context, offset string) (_ map[int]model.PoopStreak, err error) {
+	ctx, end := r.start(ctx, "GetLongestPoopStreakPerYear")
+	defer func() { end(err) }()
+	return r.Repository.GetLongestPoopStreakPerYear(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetCurrentStreak(ctx context.Context, offset string) (_ model.PoopStreak, err error) {
+	ctx, end := r.start(ctx, "GetCurrentStreak")
+	defer func() { end(err) }()
+	return r.Repository.GetCurrentStreak(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetMonthWithMostPoop(ctx context.Context, offset string) (_ model.MostPoopInADate, err error) {
+	ctx, end := r.start(ctx, "GetMonthWithMostPoop")
+	defer func() { end(err) }()
+	return r.Repository.GetMonthWithMostPoop(ctx, offset)
+}
+
+func (r *instrumentedRepository) GetMonthsWithMostPoop(ctx context.Context, offset string, n int) (_ []model.MostPoopInADate, err error) {
+	ctx, end := r.start(ctx, "GetMonthsWithMostPoop")
+	defer func() { end(err) }()
+	return r.Repository.GetMonthsWithMostPoop(ctx, offset, n)
+}
+
+func (r *instrumentedRepository) AddGoal(ctx context.Context, kind model.GoalKind, target int) (_ model.Goal, err error) {
+	ctx, end := r.start(ctx, "AddGoal")
+	defer func() { end(err) }()
+	return r.Repository.AddGoal(ctx, kind, target)
+}
+
+func (r *instrumentedRepository) GetGoals(ctx context.Context) (_ []model.Goal, err error) {
+	ctx, end := r.start(ctx, "GetGoals")
+	defer func() { end(err) }()
+	return r.Repository.GetGoals(ctx)
+}
+
+func (r *instrumentedRepository) GetGoal(ctx context.Context, id int64) (_ model.Goal, err error) {
+	ctx, end := r.start(ctx, "GetGoal")
+	defer func() { end(err) }()
+	return r.Repository.GetGoal(ctx, id)
+}
+
+func (r *instrumentedRepository) UpdateGoal(ctx context.Context, id int64, kind model.GoalKind, target int) (_ model.Goal, err error) {
+	ctx, end := r.start(ctx, "UpdateGoal")
+	defer func() { end(err) }()
+	return r.Repository.UpdateGoal(ctx, id, kind, target)
+}
+
+func (r *instrumentedRepository) DeleteGoal(ctx context.Context, id int64) (err error) {
+	ctx, end := r.start(ctx, "DeleteGoal")
+	defer func() { end(err) }()
+	return r.Repository.DeleteGoal(ctx, id)
+}
+
+func (r *instrumentedRepository) UnlockAchievement(ctx context.Context, badgeID string) (_ bool, err error) {
+	ctx, end := r.start(ctx, "UnlockAchievement")
+	defer func() { end(err) }()
+	return r.Repository.UnlockAchievement(ctx, badgeID)
+}
+
+func (r *instrumentedRepository) GetUnlockedAchievements(ctx context.Context, offset string) (_ map[string]time.Time, err error) {
+	ctx, end := r.start(ctx, "GetUnlockedAchievements")
+	defer func() { end(err) }()
+	return r.Repository.GetUnlockedAchievements(ctx, offset)
+}
+
+// CollectMetrics reads the domain gauges: the number of 💩s, the current
+// streak and the seconds since the last 💩, which is left out if there's none.
+func (s *berakService) CollectMetrics(ctx context.Context) ([]metrics.Sample, error) {
+	offset := s.offset.Load()
+	count, err := s.repo.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("count poops: %w", err)
+	}
+	streak, err := s.repo.GetCurrentStreak(ctx, offset.String())
+	if err != nil {
+		return nil, fmt.Errorf("get current streak: %w", err)
+	}
+	samples := []metrics.Sample{
+		{Name: "berak_poops", Help: "Number of 💩s logged.", Value: float64(count)},
+		{Name: "berak_current_streak_days", Help: "Days in a row with a 💩, up to today or yesterday.", Value: float64(streak.DayCount)},
+	}
+
+	last, err := s.repo.GetLastDataTimestamp(ctx, offset.String())
+	if err != nil {
+		return nil, fmt.Errorf("get last poop timestamp: %w", err)
+	}
+	if !last.IsZero() {
+		since := offset.Apply(time.Now().UTC()).Sub(last)
+		samples = append(samples, metrics.Sample{Name: "berak_seconds_since_last_poop", Help: "Seconds since the last 💩.", Value: since.Seconds()})
+	}
+	return samples, nil
+}
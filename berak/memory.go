@@ -0,0 +1,449 @@
+package berak
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/model"
+)
+
+type memoryPoop struct {
+	id        int64
+	timestamp time.Time
+}
+
+type memoryDay struct {
+	date  time.Time
+	count int
+}
+
+// memoryRepository keeps everything in memory, with the same semantics as
+// the SQL repositories. It's meant for tests and demos.
+type memoryRepository struct {
+	mu           sync.Mutex
+	poops        []memoryPoop
+	lastPoopID   int64
+	goals        []model.Goal
+	lastGoalID   int64
+	achievements map[string]time.Time
+}
+
+func NewMemoryRepo() *memoryRepository {
+	return &memoryRepository{
+		achievements: make(map[string]time.Time),
+	}
+}
+
+func (r *memoryRepository) Add(ctx context.Context) error {
+	return r.AddWithDate(ctx, time.Now())
+}
+
+func (r *memoryRepository) AddWithDate(ctx context.Context, t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastPoopID++
+	// timestamps are stored with second precision, like CURRENT_TIMESTAMP.
+	r.poops = append(r.poops, memoryPoop{id: r.lastPoopID, timestamp: t.UTC().Truncate(time.Second)})
+	return nil
+}
+
+func (r *memoryRepository) DeleteLast(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.poops) > 0 {
+		r.poops = r.poops[:len(r.poops)-1]
+	}
+	return nil
+}
+
+func (r *memoryRepository) Count(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.poops), nil
+}
+
+// timestamps returns every 💩 with offset applied, sorted by time.
+func (r *memoryRepository) timestamps(offset string) ([]time.Time, error) {
+	o, err := helper.NewOffset(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := make([]time.Time, 0, len(r.poops))
+	for _, p := range r.poops {
+		data = append(data, o.Apply(p.timestamp))
+	}
+	slices.SortStableFunc(data, time.Time.Compare)
+
+	return data, nil
+}
+
+// days returns the number of 💩s of every day with any, sorted by date.
+func (r *memoryRepository) days(offset string) ([]memoryDay, error) {
+	timestamps, err := r.timestamps(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []memoryDay
+	for _, t := range timestamps {
+		date := helper.TruncateToDay(t)
+		if len(data) > 0 && data[len(data)-1].date.Equal(date) {
+			data[len(data)-1].count++
+			continue
+		}
+		data = append(data, memoryDay{date: date, count: 1})
+	}
+
+	return data, nil
+}
+
+// streaks groups days into runs of consecutive days, oldest first. With
+// splitYears, runs crossing the new year are split at it.
+func streaks(days []memoryDay, splitYears bool) []model.PoopStreak {
+	var data []model.PoopStreak
+	for _, d := range days {
+		if len(data) > 0 {
+			last := &data[len(data)-1]
+			if last.EndDate.AddDate(0, 0, 1).Equal(d.date) && (!splitYears || last.EndDate.Year() == d.date.Year()) {
+				last.EndDate = d.date
+				last.DayCount++
+				last.PoopCount += d.count
+				continue
+			}
+		}
+		data = append(data, model.PoopStreak{StartDate: d.date, EndDate: d.date, DayCount: 1, PoopCount: d.count})
+	}
+
+	return data
+}
+
+// compareStreaks orders longer streaks first, and the latest one first among
+// streaks of the same length.
+func compareStreaks(a, b model.PoopStreak) int {
+	return cmp.Or(cmp.Compare(b.DayCount, a.DayCount), b.EndDate.Compare(a.EndDate))
+}
+
+// limit returns the first n items of s, or all of them if n is negative like
+// SQL's LIMIT.
+func limit[T any](s []T, n int) []T {
+	if n < 0 || n >= len(s) {
+		return s
+	}
+	return s[:n]
+}
+
+func (r *memoryRepository) GetMonthlyByYear(ctx context.Context, year uint64, offset string) ([]model.AggData, error) {
+	timestamps, err := r.timestamps(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []model.AggData
+	for _, t := range timestamps {
+		if uint64(t.Year()) != year {
+			continue
+		}
+		if len(data) > 0 && data[len(data)-1].Period == int(t.Month()) {
+			data[len(data)-1].Count++
+			continue
+		}
+		data = append(data, model.AggData{Period: int(t.Month()), Count: 1})
+	}
+
+	return data, nil
+}
+
+func (r *memoryRepository) GetDailyByMonthAndYear(ctx context.Context, year, month uint64, offset string) ([]model.AggData, error) {
+	timestamps, err := r.timestamps(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []model.AggData
+	for _, t := range timestamps {
+		if uint64(t.Year()) != year || uint64(t.Month()) != month {
+			continue
+		}
+		if len(data) > 0 && data[len(data)-1].Period == t.Day() {
+			data[len(data)-1].Count++
+			continue
+		}
+		data = append(data, model.AggData{Period: t.Day(), Count: 1})
+	}
+
+	return data, nil
+}
+
+func (r *memoryRepository) GetLastDataTimestamp(ctx context.Context, offset string) (time.Time, error) {
+	timestamps, err := r.timestamps(offset)
+	if err != nil || len(timestamps) == 0 {
+		return time.Time{}, err
+	}
+
+	return timestamps[len(timestamps)-1], nil
+}
+
+func (r *memoryRepository) GetTimestamps(ctx context.Context, offset string) ([]time.Time, error) {
+	return r.timestamps(offset)
+}
+
+// GetYearlySummaries returns the monthly 💩 count of every year with data,
+// without the longest streak.
+func (r *memoryRepository) GetYearlySummaries(ctx context.Context, offset string) ([]model.YearSummary, error) {
+	timestamps, err := r.timestamps(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []model.YearSummary
+	for _, t := range timestamps {
+		if len(data) == 0 || data[len(data)-1].Year != t.Year() {
+			data = append(data, model.YearSummary{Year: t.Year()})
+		}
+		data[len(data)-1].Months[t.Month()-1]++
+		data[len(data)-1].Total++
+	}
+
+	return data, nil
+}
+
+func (r *memoryRepository) GetLongestDayWithoutPoop(ctx context.Context, offset string) (model.LongestDayWithoutPoop, error) {
+	l, err := r.GetLongestDaysWithoutPoop(ctx, offset, 1)
+	if err != nil || len(l) == 0 {
+		return model.LongestDayWithoutPoop{}, err
+	}
+
+	return l[0], nil
+}
+
+func (r *memoryRepository) GetLongestDaysWithoutPoop(ctx context.Context, offset string, n int) ([]model.LongestDayWithoutPoop, error) {
+	timestamps, err := r.timestamps(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []model.LongestDayWithoutPoop
+	for i := 1; i < len(timestamps); i++ {
+		data = append(data, model.LongestDayWithoutPoop{StartTime: timestamps[i-1], EndTime: timestamps[i]})
+	}
+	slices.SortStableFunc(data, func(a, b model.LongestDayWithoutPoop) int {
+		return cmp.Or(cmp.Compare(b.Duration(), a.Duration()), b.EndTime.Compare(a.EndTime))
+	})
+
+	return limit(data, n), nil
+}
+
+func (r *memoryRepository) GetMostPoopInADay(ctx context.Context, offset string) (model.MostPoopInADate, error) {
+	m, err := r.GetDaysWithMostPoop(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.MostPoopInADate{}, err
+	}
+
+	return m[0], nil
+}
+
+func (r *memoryRepository) GetDaysWithMostPoop(ctx context.Context, offset string, n int) ([]model.MostPoopInADate, error) {
+	days, err := r.days(offset)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(days, func(a, b memoryDay) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), b.date.Compare(a.date))
+	})
+
+	var data []model.MostPoopInADate
+	for _, d := range limit(days, n) {
+		data = append(data, model.MostPoopInADate{Year: d.date.Year(), Month: int(d.date.Month()), Day: d.date.Day(), Count: d.count})
+	}
+
+	return data, nil
+}
+
+func (r *memoryRepository) GetLongestPoopStreak(ctx context.Context, offset string) (model.PoopStreak, error) {
+	m, err := r.GetLongestPoopStreaks(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.PoopStreak{}, err
+	}
+
+	return m[0], nil
+}
+
+func (r *memoryRepository) GetLongestPoopStreaks(ctx context.Context, offset string, n int) ([]model.PoopStreak, error) {
+	days, err := r.days(offset)
+	if err != nil {
+		return nil, err
+	}
+	data := streaks(days, false)
+	slices.SortStableFunc(data, compareStreaks)
+
+	return limit(data, n), nil
+}
+
+// GetLongestPoopStreakPerYear returns the longest streak of every year with
+// data, keyed by year. Streaks crossing the new year are split at it.
+func (r *memoryRepository) GetLongestPoopStreakPerYear(ctx context.Context, offset string) (map[int]model.PoopStreak, error) {
+	days, err := r.days(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[int]model.PoopStreak)
+	for _, s := range streaks(days, true) {
+		longest, ok := data[s.StartDate.Year()]
+		if !ok || compareStreaks(s, longest) < 0 {
+			data[s.StartDate.Year()] = s
+		}
+	}
+
+	return data, nil
+}
+
+func (r *memoryRepository) GetCurrentStreak(ctx context.Context, offset string) (model.PoopStreak, error) {
+	o, err := helper.NewOffset(offset)
+	if err != nil {
+		return model.PoopStreak{}, err
+	}
+	days, err := r.days(offset)
+	if err != nil {
+		return model.PoopStreak{}, err
+	}
+
+	today := helper.TruncateToDay(o.Apply(time.Now().UTC()))
+	s := streaks(days, false)
+	if len(s) == 0 {
+		return model.PoopStreak{}, nil
+	}
+	if last := s[len(s)-1]; last.EndDate.Equal(today) || last.EndDate.Equal(today.AddDate(0, 0, -1)) {
+		return last, nil
+	}
+
+	return model.PoopStreak{}, nil
+}
+
+func (r *memoryRepository) GetMonthWithMostPoop(ctx context.Context, offset string) (model.MostPoopInADate, error) {
+	m, err := r.GetMonthsWithMostPoop(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.MostPoopInADate{}, err
+	}
+
+	return m[0], nil
+}
+
+func (r *memoryRepository) GetMonthsWithMostPoop(ctx context.Context, offset string, n int) ([]model.MostPoopInADate, error) {
+	timestamps, err := r.timestamps(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []model.MostPoopInADate
+	for _, t := range timestamps {
+		if len(data) > 0 && data[len(data)-1].Year == t.Year() && data[len(data)-1].Month == int(t.Month()) {
+			data[len(data)-1].Count++
+			continue
+		}
+		data = append(data, model.MostPoopInADate{Year: t.Year(), Month: int(t.Month()), Count: 1})
+	}
+	slices.SortStableFunc(data, func(a, b model.MostPoopInADate) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(b.Year, a.Year), cmp.Compare(b.Month, a.Month))
+	})
+
+	return limit(data, n), nil
+}
+
+func (r *memoryRepository) AddGoal(ctx context.Context, kind model.GoalKind, target int) (model.Goal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastGoalID++
+	g := model.Goal{ID: r.lastGoalID, Kind: kind, Target: target, CreatedAt: time.Now().UTC().Truncate(time.Second)}
+	r.goals = append(r.goals, g)
+
+	return g, nil
+}
+
+func (r *memoryRepository) GetGoals(ctx context.Context) ([]model.Goal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.goals), nil
+}
+
+func (r *memoryRepository) goalIndex(id int64) (int, error) {
+	i := slices.IndexFunc(r.goals, func(g model.Goal) bool { return g.ID == id })
+	if i < 0 {
+		return 0, fmt.Errorf("goal[id=%d]: %w", id, sql.ErrNoRows)
+	}
+	return i, nil
+}
+
+func (r *memoryRepository) GetGoal(ctx context.Context, id int64) (model.Goal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.goalIndex(id)
+	if err != nil {
+		return model.Goal{}, err
+	}
+
+	return r.goals[i], nil
+}
+
+func (r *memoryRepository) UpdateGoal(ctx context.Context, id int64, kind model.GoalKind, target int) (model.Goal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.goalIndex(id)
+	if err != nil {
+		return model.Goal{}, err
+	}
+	r.goals[i].Kind = kind
+	r.goals[i].Target = target
+
+	return r.goals[i], nil
+}
+
+func (r *memoryRepository) DeleteGoal(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.goalIndex(id)
+	if err != nil {
+		return err
+	}
+	r.goals = slices.Delete(r.goals, i, i+1)
+
+	return nil
+}
+
+// UnlockAchievement stores the unlock time of a badge, returning false if the
+// badge was already unlocked before.
+func (r *memoryRepository) UnlockAchievement(ctx context.Context, badgeID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.achievements[badgeID]; ok {
+		return false, nil
+	}
+	r.achievements[badgeID] = time.Now().UTC().Truncate(time.Second)
+
+	return true, nil
+}
+
+func (r *memoryRepository) GetUnlockedAchievements(ctx context.Context, offset string) (map[string]time.Time, error) {
+	o, err := helper.NewOffset(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := make(map[string]time.Time, len(r.achievements))
+	for badgeID, unlockedAt := range r.achievements {
+		data[badgeID] = o.Apply(unlockedAt)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,608 @@
+package berak
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thansetan/berak/db"
+	"github.com/thansetan/berak/model"
+)
+
+// postgresRepository mirrors sqliteRepository. Times are formatted with
+// to_char so they're parsed the same way as SQLite's, and offset is cast to
+// an interval, which accepts the same "+7 hours" format as SQLite's
+// modifiers.
+type postgresRepository struct {
+	db *db.Conn
+}
+
+func NewPostgresRepo(db *db.Conn) *postgresRepository {
+	return &postgresRepository{db}
+}
+
+func (r *postgresRepository) Add(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+	INSERT INTO berak DEFAULT VALUES`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) AddWithDate(ctx context.Context, t time.Time) error {
+	// the column has no time zone, so t has to be in UTC already.
+	_, err := r.db.ExecContext(ctx, "INSERT INTO berak(timestamp) VALUES(?)", t.UTC())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) DeleteLast(ctx context.Context) error {
+	res, err := r.db.ExecContext(ctx, `
+	DELETE FROM berak WHERE id = (SELECT MAX(id) FROM berak)`)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n > 1 {
+		return fmt.Errorf("more than 1 data deleted: %d", n)
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) GetMonthlyByYear(ctx context.Context, year uint64, offset string) ([]model.AggData, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			id,
+			timestamp + CAST(? AS INTERVAL) AS timestamp
+		FROM berak
+	)
+	SELECT
+		CAST(EXTRACT(MONTH FROM timestamp) AS INTEGER) AS month,
+		COUNT(id)
+	FROM timestamp_with_offset
+	WHERE EXTRACT(YEAR FROM timestamp) = ?
+	GROUP BY month
+	ORDER BY month`, offset, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.AggData
+	for rows.Next() {
+		var monthlyData model.AggData
+		err = rows.Scan(&monthlyData.Period, &monthlyData.Count)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, monthlyData)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetDailyByMonthAndYear(ctx context.Context, year, month uint64, offset string) ([]model.AggData, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			id,
+			timestamp + CAST(? AS INTERVAL) AS timestamp
+		FROM berak
+	)
+	SELECT
+		CAST(EXTRACT(DAY FROM timestamp) AS INTEGER) AS day,
+		COUNT(id)
+	FROM timestamp_with_offset
+	WHERE EXTRACT(YEAR FROM timestamp) = ? AND EXTRACT(MONTH FROM timestamp) = ?
+	GROUP BY day
+	ORDER BY day`, offset, year, month)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.AggData
+	for rows.Next() {
+		var dailyData model.AggData
+		err = rows.Scan(&dailyData.Period, &dailyData.Count)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, dailyData)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetLastDataTimestamp(ctx context.Context, offset string) (time.Time, error) {
+	var lastPoopTime sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+	SELECT to_char(timestamp + CAST(? AS INTERVAL), 'YYYY-MM-DD HH24:MI:SS')
+	FROM berak ORDER BY timestamp DESC LIMIT 1`, offset).Scan(&lastPoopTime)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+	if !lastPoopTime.Valid {
+		return time.Time{}, nil
+	}
+	lastInsertAt, err := time.Parse(dateTimeLayout, lastPoopTime.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse lastInsertedAt: %w", err)
+	}
+
+	return lastInsertAt, nil
+}
+
+func (r *postgresRepository) GetLongestDayWithoutPoop(ctx context.Context, offset string) (model.LongestDayWithoutPoop, error) {
+	l, err := r.GetLongestDaysWithoutPoop(ctx, offset, 1)
+	if err != nil || len(l) == 0 {
+		return model.LongestDayWithoutPoop{}, err
+	}
+
+	return l[0], nil
+}
+
+func (r *postgresRepository) GetLongestDaysWithoutPoop(ctx context.Context, offset string, n int) ([]model.LongestDayWithoutPoop, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH gaps AS (
+		SELECT
+			timestamp + CAST(? AS INTERVAL) AS timestamp,
+			LAG(timestamp + CAST(? AS INTERVAL)) OVER (ORDER BY timestamp) AS prev_timestamp
+		FROM berak
+	)
+	SELECT
+		to_char(timestamp, 'YYYY-MM-DD HH24:MI:SS'),
+		to_char(prev_timestamp, 'YYYY-MM-DD HH24:MI:SS')
+	FROM gaps
+	WHERE prev_timestamp IS NOT NULL
+	ORDER BY timestamp - prev_timestamp DESC, timestamp DESC
+	LIMIT ?`, offset, offset, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.LongestDayWithoutPoop
+	for rows.Next() {
+		var (
+			l                  model.LongestDayWithoutPoop
+			startTime, endTime string
+		)
+		err = rows.Scan(&endTime, &startTime)
+		if err != nil {
+			return nil, err
+		}
+		l.StartTime, err = time.Parse(dateTimeLayout, startTime)
+		if err != nil {
+			return nil, fmt.Errorf("parse startTime: %w", err)
+		}
+		l.EndTime, err = time.Parse(dateTimeLayout, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("parse endTime: %w", err)
+		}
+		data = append(data, l)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetMostPoopInADay(ctx context.Context, offset string) (model.MostPoopInADate, error) {
+	m, err := r.GetDaysWithMostPoop(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.MostPoopInADate{}, err
+	}
+
+	return m[0], nil
+}
+
+func (r *postgresRepository) GetDaysWithMostPoop(ctx context.Context, offset string, n int) ([]model.MostPoopInADate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH poop_per_day AS (
+		SELECT
+			CAST(timestamp + CAST(? AS INTERVAL) AS DATE) AS poop_date,
+			COUNT(id) AS jumlah
+		FROM berak
+		GROUP BY poop_date
+	)
+	SELECT
+		CAST(EXTRACT(YEAR FROM poop_date) AS INTEGER),
+		CAST(EXTRACT(MONTH FROM poop_date) AS INTEGER),
+		CAST(EXTRACT(DAY FROM poop_date) AS INTEGER),
+		jumlah
+	FROM poop_per_day
+	ORDER BY jumlah DESC, poop_date DESC
+	LIMIT ?`, offset, n)
+	if err != nil {
+		return nil, fmt.Errorf("fetching most poop in a day: %w", err)
+	}
+	defer rows.Close()
+
+	var data []model.MostPoopInADate
+	for rows.Next() {
+		var m model.MostPoopInADate
+		err = rows.Scan(&m.Year, &m.Month, &m.Day, &m.Count)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, m)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetLongestPoopStreak(ctx context.Context, offset string) (model.PoopStreak, error) {
+	m, err := r.GetLongestPoopStreaks(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.PoopStreak{}, err
+	}
+
+	return m[0], nil
+}
+
+// postgresStreaks groups consecutive days with 💩s into streaks. Subtracting
+// the row number from the date gives the same date for every day of a streak.
+const postgresStreaks = `
+	WITH poop_per_day AS (SELECT CAST(timestamp + CAST(? AS INTERVAL) AS DATE) AS poop_date,
+	                             COUNT(timestamp)                               AS poop_count
+	                      FROM berak
+	                      GROUP BY poop_date),
+	     grouped_poop AS (SELECT poop_date,
+	                             poop_count,
+	                             poop_date - CAST(ROW_NUMBER() OVER (ORDER BY poop_date) AS INTEGER) AS "group"
+	                      FROM poop_per_day),
+	     streaks AS (SELECT MIN(poop_date)   AS start_date,
+	                        MAX(poop_date)   AS end_date,
+	                        COUNT(poop_date) AS day_count,
+	                        SUM(poop_count)  AS poop_count
+	                 FROM grouped_poop
+	                 GROUP BY "group")`
+
+func (r *postgresRepository) GetLongestPoopStreaks(ctx context.Context, offset string, n int) ([]model.PoopStreak, error) {
+	rows, err := r.db.QueryContext(ctx, postgresStreaks+`
+	SELECT to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), day_count, poop_count
+	FROM streaks
+	ORDER BY day_count DESC, end_date DESC LIMIT ?`, offset, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.PoopStreak
+	for rows.Next() {
+		var (
+			startDate, endDate string
+			m                  model.PoopStreak
+		)
+		err = rows.Scan(&startDate, &endDate, &m.DayCount, &m.PoopCount)
+		if err != nil {
+			return nil, err
+		}
+		m.StartDate, err = time.Parse(dateLayout, startDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse startDate: %w", err)
+		}
+		m.EndDate, err = time.Parse(dateLayout, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse endDate: %w", err)
+		}
+		data = append(data, m)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetCurrentStreak(ctx context.Context, offset string) (model.PoopStreak, error) {
+	var (
+		poopStreak         model.PoopStreak
+		startDate, endDate sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx, postgresStreaks+`
+	SELECT to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), day_count, poop_count
+	FROM streaks
+	WHERE end_date >= CAST(NOW() AT TIME ZONE 'UTC' + CAST(? AS INTERVAL) AS DATE) - 1
+	ORDER BY end_date DESC
+	LIMIT 1`, offset).Scan(&startDate, &endDate, &poopStreak.DayCount, &poopStreak.PoopCount)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return poopStreak, fmt.Errorf("fetching current streak: %w", err)
+	}
+	if startDate.Valid {
+		poopStreak.StartDate, err = time.Parse(dateLayout, startDate.String)
+		if err != nil {
+			return model.PoopStreak{}, fmt.Errorf("parse startDate: %w", err)
+		}
+	}
+	if endDate.Valid {
+		poopStreak.EndDate, err = time.Parse(dateLayout, endDate.String)
+		if err != nil {
+			return model.PoopStreak{}, fmt.Errorf("parse endDate: %w", err)
+		}
+	}
+
+	return poopStreak, nil
+}
+
+func (r *postgresRepository) GetMonthWithMostPoop(ctx context.Context, offset string) (model.MostPoopInADate, error) {
+	m, err := r.GetMonthsWithMostPoop(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.MostPoopInADate{}, err
+	}
+
+	return m[0], nil
+}
+
+func (r *postgresRepository) GetMonthsWithMostPoop(ctx context.Context, offset string, n int) ([]model.MostPoopInADate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			timestamp + CAST(? AS INTERVAL) AS timestamp
+		FROM berak
+	)
+	SELECT
+		CAST(EXTRACT(YEAR FROM timestamp) AS INTEGER) AS year,
+		CAST(EXTRACT(MONTH FROM timestamp) AS INTEGER) AS month,
+		COUNT(1) AS cnt
+	FROM timestamp_with_offset
+	GROUP BY year, month
+	ORDER BY cnt DESC, year DESC, month DESC LIMIT ?`, offset, n)
+	if err != nil {
+		return nil, fmt.Errorf("fetching month with most poop: %w", err)
+	}
+	defer rows.Close()
+
+	var data []model.MostPoopInADate
+	for rows.Next() {
+		var m model.MostPoopInADate
+		err = rows.Scan(&m.Year, &m.Month, &m.Count)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, m)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) AddGoal(ctx context.Context, kind model.GoalKind, target int) (model.Goal, error) {
+	var g model.Goal
+	err := r.db.QueryRowContext(ctx, `
+	INSERT INTO goals(kind, target) VALUES(?, ?)
+	RETURNING id, kind, target, created_at`, kind, target).Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt)
+	if err != nil {
+		return model.Goal{}, err
+	}
+
+	return g, nil
+}
+
+func (r *postgresRepository) GetGoals(ctx context.Context) ([]model.Goal, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT id, kind, target, created_at FROM goals ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.Goal
+	for rows.Next() {
+		var g model.Goal
+		err = rows.Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, g)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetGoal(ctx context.Context, id int64) (model.Goal, error) {
+	var g model.Goal
+	err := r.db.QueryRowContext(ctx, `
+	SELECT id, kind, target, created_at FROM goals WHERE id = ?`, id).Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt)
+	if err != nil {
+		return model.Goal{}, err
+	}
+
+	return g, nil
+}
+
+func (r *postgresRepository) UpdateGoal(ctx context.Context, id int64, kind model.GoalKind, target int) (model.Goal, error) {
+	var g model.Goal
+	err := r.db.QueryRowContext(ctx, `
+	UPDATE goals SET kind = ?, target = ? WHERE id = ?
+	RETURNING id, kind, target, created_at`, kind, target, id).Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt)
+	if err != nil {
+		return model.Goal{}, err
+	}
+
+	return g, nil
+}
+
+func (r *postgresRepository) DeleteGoal(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `
+	DELETE FROM goals WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) Count(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+	SELECT COUNT(1) FROM berak`).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (r *postgresRepository) UnlockAchievement(ctx context.Context, badgeID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+	INSERT INTO achievements(badge_id) VALUES(?) ON CONFLICT DO NOTHING`, badgeID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *postgresRepository) GetUnlockedAchievements(ctx context.Context, offset string) (map[string]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT badge_id, to_char(unlocked_at + CAST(? AS INTERVAL), 'YYYY-MM-DD HH24:MI:SS') FROM achievements`, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := make(map[string]time.Time)
+	for rows.Next() {
+		var badgeID, unlockedAt string
+		err = rows.Scan(&badgeID, &unlockedAt)
+		if err != nil {
+			return nil, err
+		}
+		data[badgeID], err = time.Parse(dateTimeLayout, unlockedAt)
+		if err != nil {
+			return nil, fmt.Errorf("parse unlockedAt: %w", err)
+		}
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetTimestamps(ctx context.Context, offset string) ([]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT to_char(timestamp + CAST(? AS INTERVAL), 'YYYY-MM-DD HH24:MI:SS') FROM berak ORDER BY timestamp`, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []time.Time
+	for rows.Next() {
+		var timestamp string
+		err = rows.Scan(&timestamp)
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(dateTimeLayout, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("parse timestamp: %w", err)
+		}
+		data = append(data, t)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetYearlySummaries(ctx context.Context, offset string) ([]model.YearSummary, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			id,
+			timestamp + CAST(? AS INTERVAL) AS timestamp
+		FROM berak
+	)
+	SELECT
+		CAST(EXTRACT(YEAR FROM timestamp) AS INTEGER) AS year,
+		CAST(EXTRACT(MONTH FROM timestamp) AS INTEGER) AS month,
+		COUNT(id)
+	FROM timestamp_with_offset
+	GROUP BY year, month
+	ORDER BY year, month`, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.YearSummary
+	for rows.Next() {
+		var year, month, count int
+		err = rows.Scan(&year, &month, &count)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 || data[len(data)-1].Year != year {
+			data = append(data, model.YearSummary{Year: year})
+		}
+		data[len(data)-1].Months[month-1] = count
+		data[len(data)-1].Total += count
+	}
+
+	return data, rows.Err()
+}
+
+func (r *postgresRepository) GetLongestPoopStreakPerYear(ctx context.Context, offset string) (map[int]model.PoopStreak, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH poop_per_day AS (SELECT CAST(timestamp + CAST(? AS INTERVAL) AS DATE) AS poop_date,
+	                             COUNT(timestamp)                               AS poop_count
+	                      FROM berak
+	                      GROUP BY poop_date),
+	     grouped_poop AS (SELECT poop_date,
+	                             poop_count,
+	                             CAST(EXTRACT(YEAR FROM poop_date) AS INTEGER) AS year,
+	                             poop_date - CAST(ROW_NUMBER() OVER (PARTITION BY EXTRACT(YEAR FROM poop_date) ORDER BY poop_date) AS INTEGER)
+	                                 AS "group"
+	                      FROM poop_per_day),
+	     streaks AS (SELECT year,
+	                        MIN(poop_date)   AS start_date,
+	                        MAX(poop_date)   AS end_date,
+	                        COUNT(poop_date) AS day_count,
+	                        SUM(poop_count)  AS poop_count,
+	                        ROW_NUMBER() OVER (PARTITION BY year ORDER BY COUNT(poop_date) DESC, MAX(poop_date) DESC) AS rn
+	                 FROM grouped_poop
+	                 GROUP BY year, "group")
+	SELECT year, to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), day_count, poop_count
+	FROM streaks
+	WHERE rn = 1`, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := make(map[int]model.PoopStreak)
+	for rows.Next() {
+		var (
+			year               int
+			startDate, endDate string
+			m                  model.PoopStreak
+		)
+		err = rows.Scan(&year, &startDate, &endDate, &m.DayCount, &m.PoopCount)
+		if err != nil {
+			return nil, err
+		}
+		m.StartDate, err = time.Parse(dateLayout, startDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse startDate: %w", err)
+		}
+		m.EndDate, err = time.Parse(dateLayout, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse endDate: %w", err)
+		}
+		data[year] = m
+	}
+
+	return data, rows.Err()
+}
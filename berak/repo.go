@@ -2,316 +2,56 @@ package berak
 
 import (
 	"context"
-	"database/sql"
-	"errors"
-	"fmt"
 	"time"
 
+	"github.com/thansetan/berak/db"
 	"github.com/thansetan/berak/model"
 )
 
-const (
-	dateLayout     = "2006-01-02"
-	dateTimeLayout = "2006-01-02 15:04:05"
-)
-
-type berakRepository struct {
-	db *sql.DB
-}
-
-func NewRepo(db *sql.DB) *berakRepository {
-	return &berakRepository{db}
-}
-
-func (r *berakRepository) Add(ctx context.Context) error {
-	_, err := r.db.ExecContext(ctx, `
-	INSERT INTO berak DEFAULT VALUES`)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (r *berakRepository) AddWithDate(ctx context.Context, t time.Time) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO berak(timestamp) VALUES(?)", t)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (r *berakRepository) DeleteLast(ctx context.Context) error {
-	res, err := r.db.ExecContext(ctx, `
-	DELETE FROM berak WHERE id = (SELECT MAX(id) FROM berak)`)
-	if err != nil {
-		return err
-	}
-	if n, err := res.RowsAffected(); err != nil {
-		return err
-	} else if n > 1 {
-		return fmt.Errorf("more than 1 data deleted: %d", n)
-	}
-
-	return nil
-}
-
-func (r *berakRepository) GetMonthlyByYear(ctx context.Context, year uint64, offset string) ([]model.AggData, error) {
-	rows, err := r.db.QueryContext(ctx, `
-	WITH timestamp_with_offset AS (
-		SELECT
-			id,
-			DATETIME(timestamp, ?) timestamp
-		FROM berak
-	)
-	SELECT
-		strftime('%m', timestamp) month,
-		COUNT(id)
-	FROM timestamp_with_offset
-	WHERE strftime('%Y', timestamp) = ?
-	GROUP BY month
-	ORDER BY month;`, offset, fmt.Sprintf("%04d", year))
-	if err != nil {
-		return nil, err
-	}
-
-	var data []model.AggData
-	for rows.Next() {
-		var monthlyData model.AggData
-		err = rows.Scan(&monthlyData.Period, &monthlyData.Count)
-		if err != nil {
-			return nil, err
-		}
-		data = append(data, monthlyData)
-	}
-
-	return data, nil
-}
-
-func (r *berakRepository) GetDailyByMonthAndYear(ctx context.Context, year, month uint64, offset string) ([]model.AggData, error) {
-	rows, err := r.db.QueryContext(ctx, `
-	WITH timestamp_with_offset AS (
-		SELECT
-			id,
-			DATETIME(timestamp, ?) timestamp
-		FROM berak
-	)
-	SELECT
-		strftime('%d', timestamp) day,
-		COUNT(id)
-	FROM timestamp_with_offset
-	WHERE strftime('%Y', timestamp) = ? AND strftime('%m', timestamp) = ?
-	GROUP BY day
-	ORDER BY day;`, offset, fmt.Sprintf("%04d", year), fmt.Sprintf("%02d", month))
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return nil, err
-	}
-
-	var data []model.AggData
-	for rows.Next() {
-		var dailyData model.AggData
-		err = rows.Scan(&dailyData.Period, &dailyData.Count)
-		if err != nil {
-			return nil, err
-		}
-		data = append(data, dailyData)
-	}
-
-	return data, nil
-}
-
-func (r *berakRepository) GetLastDataTimestamp(ctx context.Context, offset string) (time.Time, error) {
-	var lastPoopTime sql.NullString
-	err := r.db.QueryRowContext(ctx, `
-	WITH timestamp_with_offset AS (
-		SELECT
-			id,
-			DATETIME(timestamp, ?) timestamp
-		FROM berak
-	)
-	SELECT timestamp FROM timestamp_with_offset ORDER BY timestamp DESC LIMIT 1`, offset).Scan(&lastPoopTime)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return time.Time{}, err
-	}
-	if !lastPoopTime.Valid {
-		return time.Time{}, nil
-	}
-	lastInsertAt, err := time.Parse(dateTimeLayout, lastPoopTime.String)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("parse lastInsertedAt: %w", err)
-	}
-
-	return lastInsertAt, nil
-}
-
-func (r *berakRepository) GetLongestDayWithoutPoop(ctx context.Context, offset string) (model.LongestDayWithoutPoop, error) {
-	var startTime, endTime sql.NullString
-	err := r.db.QueryRowContext(ctx, `
-		SELECT
-    		DATETIME(timestamp, ?) timestamp,
-    		LAG(DATETIME(timestamp, ?)) OVER (ORDER BY timestamp) prev_timestamp
-		FROM berak
-		ORDER BY JULIANDAY(timestamp) - JULIANDAY(prev_timestamp) DESC LIMIT 1
-		`, offset, offset).Scan(&endTime, &startTime)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return model.LongestDayWithoutPoop{}, err
-	}
-
-	var l model.LongestDayWithoutPoop
-
-	if startTime.Valid {
-		l.StartTime, err = time.Parse(dateTimeLayout, startTime.String)
-		if err != nil {
-			return model.LongestDayWithoutPoop{}, fmt.Errorf("parse startTime: %s", err)
-		}
-	}
-	if endTime.Valid {
-		l.EndTime, err = time.Parse(dateTimeLayout, endTime.String)
-		if err != nil {
-			return model.LongestDayWithoutPoop{}, fmt.Errorf("parse endTime: %s", err)
-		}
-	}
-
-	return l, nil
-}
-
-func (r *berakRepository) GetMostPoopInADay(ctx context.Context, offset string) (model.MostPoopInADate, error) {
-	var m model.MostPoopInADate
-	err := r.db.QueryRowContext(ctx, `
-		WITH timestamp_with_offset AS (SELECT id,
-		                                      DATETIME(timestamp, ?) timestamp
-		                               FROM berak)
-		SELECT 
-				STRFTIME('%Y', timestamp) tahun,
-			   	STRFTIME('%m', timestamp) bulan,
-		       	STRFTIME('%d', timestamp) tanggal,
-		       	COUNT(id)                 jumlah
-		FROM timestamp_with_offset
-		GROUP BY tahun, bulan, tanggal
-		ORDER BY jumlah DESC, tahun DESC, bulan DESC, tanggal DESC
-		LIMIT 1`, offset).Scan(&m.Year, &m.Month, &m.Day, &m.Count)
-	if err != nil {
-		return model.MostPoopInADate{}, fmt.Errorf("fetching most poop in a day: %w", err)
-	}
-
-	return m, nil
-}
-
-func (r *berakRepository) GetLongestPoopStreak(ctx context.Context, offset string) (model.PoopStreak, error) {
-	var (
-		startDate, endDate sql.NullString
-		m                  model.PoopStreak
-	)
-	err := r.db.QueryRowContext(ctx, `
-	WITH poop_per_day AS (SELECT DATE(timestamp, ?) poop_date,
-                             COUNT(timestamp)            poop_count
-                      FROM berak
-                      GROUP BY poop_date),
-     grouped_poop AS (SELECT poop_date,
-                             poop_count,
-                             JULIANDAY(poop_date) - ROW_NUMBER() OVER (ORDER BY poop_date)
-                                 "group"
-                      FROM poop_per_day)
-	SELECT MIN(poop_date)   start_date,
-	       MAX(poop_date)   end_date,
-	       COUNT(poop_date) day_count,
-	       SUM(poop_count)  poop_count
-	FROM grouped_poop
-	GROUP BY "group"
-	ORDER BY day_count DESC, end_date DESC LIMIT 1`, offset).Scan(&startDate, &endDate, &m.DayCount, &m.PoopCount)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return model.PoopStreak{}, err
-	}
-	if startDate.Valid {
-		m.StartDate, err = time.Parse(dateLayout, startDate.String)
-		if err != nil {
-			return model.PoopStreak{}, fmt.Errorf("parse startDate: %w", err)
-		}
-	}
-	if endDate.Valid {
-		m.EndDate, err = time.Parse(dateLayout, endDate.String)
-		if err != nil {
-			return model.PoopStreak{}, fmt.Errorf("parse endDate: %w", err)
-		}
-	}
-
-	return m, nil
-}
-
-func (r *berakRepository) GetCurrentStreak(ctx context.Context, offset string) (model.PoopStreak, error) {
-	var (
-		poopStreak         model.PoopStreak
-		startDate, endDate sql.NullString
-	)
-	err := r.db.QueryRowContext(ctx, `
-	WITH poop_per_day AS (
-	    SELECT
-	        DATE(timestamp, ?) poop_date,
-	        COUNT(timestamp) poop_count
-	    FROM berak
-	    GROUP BY poop_date
-	),
-	grouped_poops AS (
-	    SELECT
-	        poop_date,
-	        poop_count,
-	        JULIANDAY(poop_date) - ROW_NUMBER() OVER (ORDER BY poop_date) AS group_id
-	    FROM poop_per_day
-	)
-	SELECT
-	    MIN(poop_date) AS start_date,
-	    MAX(poop_date) AS end_date,
-	    COUNT(1) AS day_count,
-	    SUM(poop_count) poop_count
-	FROM grouped_poops
-	GROUP BY group_id
-	HAVING (MAX(poop_date) = DATE('now', ?)) OR (MAX(poop_date) = DATE('now', '-1 day', ?))
-	LIMIT 1
-	`, offset, offset, offset).Scan(&startDate, &endDate, &poopStreak.DayCount, &poopStreak.PoopCount)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return poopStreak, fmt.Errorf("fetching current streak: %w", err)
-	}
-	if startDate.Valid {
-		poopStreak.StartDate, err = time.Parse(dateLayout, startDate.String)
-		if err != nil {
-			return model.PoopStreak{}, fmt.Errorf("parse startDate: %w", err)
-		}
-	}
-	if endDate.Valid {
-		poopStreak.EndDate, err = time.Parse(dateLayout, endDate.String)
-		if err != nil {
-			return model.PoopStreak{}, fmt.Errorf("parse endDate: %w", err)
-		}
-	}
-
-	return poopStreak, nil
-}
-
-func (r *berakRepository) GetMonthWithMostPoop(ctx context.Context, offset string) (model.MostPoopInADate, error) {
-	var m model.MostPoopInADate
-	err := r.db.QueryRowContext(ctx, `
-	WITH timestamp_with_offset AS (
-		SELECT
-			DATE(timestamp, ?) timestamp
-		FROM berak
-	),
-	grouped_per_year_month AS (
-		SELECT
-	        strftime('%Y', timestamp) as year,
-	        strftime('%m', timestamp) as month,
-			COUNT(1) cnt
-	    FROM timestamp_with_offset
-		GROUP BY year, month
-	)
-	SELECT
-		*
-	FROM grouped_per_year_month
-	ORDER BY cnt DESC LIMIT 1
-	`, offset).Scan(&m.Year, &m.Month, &m.Count)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return model.MostPoopInADate{}, fmt.Errorf("fetching month with most poop: %w", err)
-	}
-
-	return m, nil
+// Repository stores the 💩s, goals and achievements. offset is applied to the
+// stored UTC times before they're grouped or returned, so days and months are
+// those of the log's time zone.
+type Repository interface {
+	Add(ctx context.Context) error
+	AddWithDate(ctx context.Context, t time.Time) error
+	// DeleteLast deletes the last added 💩, which isn't necessarily the latest.
+	DeleteLast(ctx context.Context) error
+	Count(ctx context.Context) (int, error)
+
+	GetMonthlyByYear(ctx context.Context, year uint64, offset string) ([]model.AggData, error)
+	GetDailyByMonthAndYear(ctx context.Context, year, month uint64, offset string) ([]model.AggData, error)
+	GetLastDataTimestamp(ctx context.Context, offset string) (time.Time, error)
+	GetTimestamps(ctx context.Context, offset string) ([]time.Time, error)
+	GetYearlySummaries(ctx context.Context, offset string) ([]model.YearSummary, error)
+
+	GetLongestDayWithoutPoop(ctx context.Context, offset string) (model.LongestDayWithoutPoop, error)
+	GetLongestDaysWithoutPoop(ctx context.Context, offset string, n int) ([]model.LongestDayWithoutPoop, error)
+	GetMostPoopInADay(ctx context.Context, offset string) (model.MostPoopInADate, error)
+	GetDaysWithMostPoop(ctx context.Context, offset string, n int) ([]model.MostPoopInADate, error)
+	GetLongestPoopStreak(ctx context.Context, offset string) (model.PoopStreak, error)
+	GetLongestPoopStreaks(ctx context.Context, offset string, n int) ([]model.PoopStreak, error)
+	GetLongestPoopStreakPerYear(ctx context.Context, offset string) (map[int]model.PoopStreak, error)
+	// GetCurrentStreak returns the streak ending today or yesterday, if any.
+	GetCurrentStreak(ctx context.Context, offset string) (model.PoopStreak, error)
+	GetMonthWithMostPoop(ctx context.Context, offset string) (model.MostPoopInADate, error)
+	GetMonthsWithMostPoop(ctx context.Context, offset string, n int) ([]model.MostPoopInADate, error)
+
+	// GetGoal, UpdateGoal and DeleteGoal return sql.ErrNoRows for unknown ids.
+	AddGoal(ctx context.Context, kind model.GoalKind, target int) (model.Goal, error)
+	GetGoals(ctx context.Context) ([]model.Goal, error)
+	GetGoal(ctx context.Context, id int64) (model.Goal, error)
+	UpdateGoal(ctx context.Context, id int64, kind model.GoalKind, target int) (model.Goal, error)
+	DeleteGoal(ctx context.Context, id int64) error
+
+	// UnlockAchievement returns false if the badge was already unlocked.
+	UnlockAchievement(ctx context.Context, badgeID string) (bool, error)
+	GetUnlockedAchievements(ctx context.Context, offset string) (map[string]time.Time, error)
+}
+
+// NewRepo returns the Repository for the driver of conn.
+func NewRepo(conn *db.Conn) Repository {
+	if conn.Driver() == db.DriverPostgres {
+		return NewPostgresRepo(conn)
+	}
+	return NewSQLiteRepo(conn)
 }
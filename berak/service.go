@@ -5,24 +5,121 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/thansetan/berak/db"
 	"github.com/thansetan/berak/helper"
 	"github.com/thansetan/berak/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 )
 
+var (
+	ErrGoalNotFound = errors.New("goal not found")
+	ErrInvalidGoal  = errors.New("invalid goal")
+
+	ErrEvaluateAchievements = errors.New("evaluate achievements")
+)
+
+var tracer = otel.Tracer("github.com/thansetan/berak/berak")
+
+// startSpan starts the span of a service method, which end ends with the
+// error the method returned.
+func startSpan(ctx context.Context, name string) (context.Context, func(err error)) {
+	ctx, span := tracer.Start(ctx, name)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
 type berakService struct {
-	repo   *berakRepository
-	offset helper.Offset
+	repo Repository
+	// db is the database repo keeps the data in, nil if it's kept elsewhere.
+	db     *db.Conn
+	offset atomic.Pointer[helper.Offset]
+
+	mu sync.Mutex
+	// changed is closed and replaced whenever a 💩 or goal is written through
+	// the service.
+	changed chan struct{}
+	// refresh is closed and replaced whenever every page has to be rendered
+	// again, e.g. as the offset changed.
+	refresh chan struct{}
+
+	anomalies cached[[]model.Anomaly]
+	forecast  cached[model.Forecast]
+}
+
+// NewService returns a service reading and writing through repo, which keeps
+// its data in conn, if it's not kept elsewhere, e.g. in memory.
+func NewService(repo Repository, conn *db.Conn, offset helper.Offset) *berakService {
+	s := &berakService{repo: repo, db: conn, changed: make(chan struct{}), refresh: make(chan struct{})}
+	s.offset.Store(&offset)
+	return s
+}
+
+// SetOffset replaces the offset used from now on, having every page rendered
+// again if it's a different one.
+func (s *berakService) SetOffset(offset helper.Offset) {
+	old := s.offset.Swap(&offset)
+	if old.String() != offset.String() {
+		s.Refresh()
+	}
+}
+
+// Refreshed returns a channel that's closed once every page has to be
+// rendered again.
+func (s *berakService) Refreshed() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refresh
+}
+
+// Refresh has every page rendered again.
+func (s *berakService) Refresh() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.refresh)
+	s.refresh = make(chan struct{})
+}
+
+// Changed returns a channel that's closed once a 💩 or goal is written through
+// the service. Unlike watching the database file, it misses writes made by
+// other processes.
+func (s *berakService) Changed() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
 }
 
-func NewService(repo *berakRepository, offset helper.Offset) *berakService {
-	return &berakService{repo, offset}
+func (s *berakService) notifyChanged() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.changed)
+	s.changed = make(chan struct{})
 }
 
-func (s *berakService) GetMonthly(ctx context.Context, now time.Time, year uint64) (model.TableData, error) {
+// Path returns the file the data is stored in, or "" if there's none.
+func (s *berakService) Path() string {
+	if s.db == nil {
+		return ""
+	}
+	return s.db.Path()
+}
+
+func (s *berakService) GetMonthly(ctx context.Context, now time.Time, year uint64) (_ model.TableData, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetMonthly")
+	defer func() { end(err) }()
+
 	var data model.TableData
-	monthlyData, err := s.repo.GetMonthlyByYear(ctx, year, s.offset.String())
+	monthlyData, err := s.repo.GetMonthlyByYear(ctx, year, s.offset.Load().String())
 	if err != nil {
 		return data, fmt.Errorf("get monthly data: %w", err)
 	}
@@ -52,9 +149,12 @@ func (s *berakService) GetMonthly(ctx context.Context, now time.Time, year uint6
 	return data, nil
 }
 
-func (s *berakService) GetDaily(ctx context.Context, now time.Time, year uint64, month uint64) (model.TableData, error) {
+func (s *berakService) GetDaily(ctx context.Context, now time.Time, year uint64, month uint64) (_ model.TableData, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetDaily")
+	defer func() { end(err) }()
+
 	var data model.TableData
-	dailyData, err := s.repo.GetDailyByMonthAndYear(ctx, year, month, s.offset.String())
+	dailyData, err := s.repo.GetDailyByMonthAndYear(ctx, year, month, s.offset.Load().String())
 	if err != nil {
 		return data, fmt.Errorf("get daily data: %w", err)
 	}
@@ -80,20 +180,70 @@ func (s *berakService) GetDaily(ctx context.Context, now time.Time, year uint64,
 		dailyDataComplete = append(dailyDataComplete, model.AggData{Period: curr})
 	}
 
+	anomalies, err := s.GetAnomalies(ctx)
+	if err != nil {
+		return data, fmt.Errorf("get anomalies: %w", err)
+	}
+	for _, a := range anomalies {
+		if uint64(a.Date.Year()) == year && uint64(a.Date.Month()) == month && a.Date.Day() <= len(dailyDataComplete) {
+			dailyDataComplete[a.Date.Day()-1].Anomalies = append(dailyDataComplete[a.Date.Day()-1].Anomalies, a)
+		}
+	}
+
 	data.CurrentTime = now
 	data.Data = dailyDataComplete
 
 	return data, nil
 }
 
-func (s *berakService) GetStatistics(ctx context.Context) (model.Statistics, error) {
+// GetYearlySummaries returns a summary of every year from the first year with
+// data until the current year.
+func (s *berakService) GetYearlySummaries(ctx context.Context, now time.Time) (_ []model.YearSummary, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetYearlySummaries")
+	defer func() { end(err) }()
+
+	summaries, err := s.repo.GetYearlySummaries(ctx, s.offset.Load().String())
+	if err != nil {
+		return nil, fmt.Errorf("get yearly summaries: %w", err)
+	}
+	longestPoopStreaks, err := s.repo.GetLongestPoopStreakPerYear(ctx, s.offset.Load().String())
+	if err != nil {
+		return nil, fmt.Errorf("get longest poop streak per year: %w", err)
+	}
+	if len(summaries) == 0 {
+		return nil, nil
+	}
+
+	completeSummaries := make([]model.YearSummary, 0, now.Year()-summaries[0].Year+1)
+	curr := summaries[0].Year
+	for _, d := range summaries {
+		for ; curr < d.Year; curr++ {
+			completeSummaries = append(completeSummaries, model.YearSummary{Year: curr})
+		}
+		completeSummaries = append(completeSummaries, d)
+		curr++
+	}
+	for ; curr <= now.Year(); curr++ {
+		completeSummaries = append(completeSummaries, model.YearSummary{Year: curr})
+	}
+	for i := range completeSummaries {
+		completeSummaries[i].LongestPoopStreak = longestPoopStreaks[completeSummaries[i].Year]
+	}
+
+	return completeSummaries, nil
+}
+
+func (s *berakService) GetStatistics(ctx context.Context) (_ model.Statistics, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetStatistics")
+	defer func() { end(err) }()
+
 	var data model.Statistics
-	mostPoopInADay, err := s.repo.GetMostPoopInADay(ctx, s.offset.String())
+	mostPoopInADay, err := s.repo.GetMostPoopInADay(ctx, s.offset.Load().String())
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return data, fmt.Errorf("get most poop in a day: %w", err)
 	}
 
-	longestDayWithoutPoop, err := s.repo.GetLongestDayWithoutPoop(ctx, s.offset.String())
+	longestDayWithoutPoop, err := s.repo.GetLongestDayWithoutPoop(ctx, s.offset.Load().String())
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return data, fmt.Errorf("get longest day without poop: %w", err)
 	}
@@ -101,60 +251,380 @@ func (s *berakService) GetStatistics(ctx context.Context) (model.Statistics, err
 	if err != nil {
 		return data, fmt.Errorf("get last poop time: %w", err)
 	}
-	longestPoopStreak, err := s.repo.GetLongestPoopStreak(ctx, s.offset.String())
+	longestPoopStreak, err := s.repo.GetLongestPoopStreak(ctx, s.offset.Load().String())
 	if err != nil {
 		return data, fmt.Errorf("get longest poop streak: %w", err)
 	}
-	currentPoopStreak, err := s.repo.GetCurrentStreak(ctx, s.offset.String())
+	currentPoopStreak, err := s.repo.GetCurrentStreak(ctx, s.offset.Load().String())
 	if err != nil {
 		return data, fmt.Errorf("get current poop streak: %w", err)
 	}
-	monthWithMostPoop, err := s.repo.GetMonthWithMostPoop(ctx, s.offset.String())
+	monthWithMostPoop, err := s.repo.GetMonthWithMostPoop(ctx, s.offset.Load().String())
 	if err != nil {
 		return data, fmt.Errorf("get month with most poop: %w", err)
 	}
+	goals, err := s.getGoalsProgress(ctx, currentPoopStreak)
+	if err != nil {
+		return data, fmt.Errorf("get goals progress: %w", err)
+	}
+	nextPoop, err := s.GetForecast(ctx)
+	if err != nil {
+		return data, fmt.Errorf("get next poop forecast: %w", err)
+	}
+
+	ongoingDrought := s.ongoingDrought(lastPoopAt)
+	if !ongoingDrought.IsEmpty() && ongoingDrought.Duration() > longestDayWithoutPoop.Duration() {
+		longestDayWithoutPoop = ongoingDrought
+	}
 
 	data.LastPoopAt = lastPoopAt
 	data.MostPoopInADay = mostPoopInADay
 	data.LongestDayWithoutPoop = longestDayWithoutPoop
+	data.OngoingDrought = ongoingDrought
 	data.LongestPoopStreak = longestPoopStreak
 	data.CurrentStreak = currentPoopStreak
 	data.MostPoopInAMonth = monthWithMostPoop
+	data.Goals = goals
+	data.NextPoop = nextPoop
 
 	return data, nil
 }
 
-func (s *berakService) GetLastPoopTime(ctx context.Context) (time.Time, error) {
-	t, err := s.repo.GetLastDataTimestamp(ctx, s.offset.String())
+func (s *berakService) GetLastPoopTime(ctx context.Context) (_ time.Time, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetLastPoopTime")
+	defer func() { end(err) }()
+
+	t, err := s.repo.GetLastDataTimestamp(ctx, s.offset.Load().String())
 	if err != nil {
 		return time.Time{}, fmt.Errorf("get last poop timestamp: %w", err)
 	}
 	return t, nil
 }
 
-func (s *berakService) DeleteLast(ctx context.Context) error {
-	err := s.repo.DeleteLast(ctx)
+func (s *berakService) GetRecords(ctx context.Context, n int) (_ model.Records, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetRecords")
+	defer func() { end(err) }()
+
+	var data model.Records
+	longestPoopStreaks, err := s.repo.GetLongestPoopStreaks(ctx, s.offset.Load().String(), n)
+	if err != nil {
+		return data, fmt.Errorf("get longest poop streaks: %w", err)
+	}
+	longestDaysWithoutPoop, err := s.repo.GetLongestDaysWithoutPoop(ctx, s.offset.Load().String(), n)
+	if err != nil {
+		return data, fmt.Errorf("get longest days without poop: %w", err)
+	}
+	mostPoopDays, err := s.repo.GetDaysWithMostPoop(ctx, s.offset.Load().String(), n)
+	if err != nil {
+		return data, fmt.Errorf("get days with most poop: %w", err)
+	}
+	mostPoopMonths, err := s.repo.GetMonthsWithMostPoop(ctx, s.offset.Load().String(), n)
+	if err != nil {
+		return data, fmt.Errorf("get months with most poop: %w", err)
+	}
+	lastPoopAt, err := s.GetLastPoopTime(ctx)
+	if err != nil {
+		return data, fmt.Errorf("get last poop time: %w", err)
+	}
+
+	ongoingDrought := s.ongoingDrought(lastPoopAt)
+	if !ongoingDrought.IsEmpty() {
+		i := 0
+		for i < len(longestDaysWithoutPoop) && longestDaysWithoutPoop[i].Duration() >= ongoingDrought.Duration() {
+			i++
+		}
+		if i < n {
+			longestDaysWithoutPoop = append(longestDaysWithoutPoop[:i], append([]model.LongestDayWithoutPoop{ongoingDrought}, longestDaysWithoutPoop[i:]...)...)
+			longestDaysWithoutPoop = longestDaysWithoutPoop[:min(len(longestDaysWithoutPoop), n)]
+		}
+	}
+
+	assignRanks(longestPoopStreaks, func(p *model.PoopStreak) (int, *int) { return p.DayCount, &p.Rank })
+	assignRanks(longestDaysWithoutPoop, func(l *model.LongestDayWithoutPoop) (int, *int) { return int(l.Duration() / time.Minute), &l.Rank })
+	assignRanks(mostPoopDays, func(m *model.MostPoopInADate) (int, *int) { return m.Count, &m.Rank })
+	assignRanks(mostPoopMonths, func(m *model.MostPoopInADate) (int, *int) { return m.Count, &m.Rank })
+
+	data.LongestPoopStreaks = longestPoopStreaks
+	data.LongestDaysWithoutPoop = longestDaysWithoutPoop
+	data.MostPoopDays = mostPoopDays
+	data.MostPoopMonths = mostPoopMonths
+
+	return data, nil
+}
+
+// assignRanks ranks already sorted items, giving items with the same score the
+// same rank and skipping the ranks they took up (1, 2, 2, 4).
+func assignRanks[T any](items []T, f func(*T) (score int, rank *int)) {
+	var prevScore, prevRank int
+	for i := range items {
+		score, rank := f(&items[i])
+		if i > 0 && score == prevScore {
+			*rank = prevRank
+			continue
+		}
+		*rank = i + 1
+		prevScore, prevRank = score, *rank
+	}
+}
+
+// IsOngoingDroughtRecord reports whether the gap since the last 💩 is longer
+// than any gap between two recorded 💩s.
+func (s *berakService) IsOngoingDroughtRecord(ctx context.Context) (_ bool, err error) {
+	ctx, end := startSpan(ctx, "berakService.IsOngoingDroughtRecord")
+	defer func() { end(err) }()
+
+	lastPoopAt, err := s.GetLastPoopTime(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get last poop time: %w", err)
+	}
+	longestDayWithoutPoop, err := s.repo.GetLongestDayWithoutPoop(ctx, s.offset.Load().String())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("get longest day without poop: %w", err)
+	}
+	ongoingDrought := s.ongoingDrought(lastPoopAt)
+	return !ongoingDrought.IsEmpty() && ongoingDrought.Duration() > longestDayWithoutPoop.Duration(), nil
+}
+
+func (s *berakService) ongoingDrought(lastPoopAt time.Time) model.LongestDayWithoutPoop {
+	if lastPoopAt.IsZero() {
+		return model.LongestDayWithoutPoop{}
+	}
+	return model.LongestDayWithoutPoop{
+		StartTime: lastPoopAt,
+		EndTime:   s.CurrentTime(),
+		Ongoing:   true,
+	}
+}
+
+func (s *berakService) DeleteLast(ctx context.Context) (err error) {
+	ctx, end := startSpan(ctx, "berakService.DeleteLast")
+	defer func() { end(err) }()
+
+	err = s.repo.DeleteLast(ctx)
 	if err != nil {
 		return fmt.Errorf("delete last poop: %w", err)
 	}
+	s.notifyChanged()
 	return nil
 }
 
-func (s *berakService) Add(ctx context.Context, date time.Time) error {
+// Add stores a new 💩 and returns the badges it unlocked. The 💩 is stored
+// even when the returned error wraps ErrEvaluateAchievements.
+func (s *berakService) Add(ctx context.Context, date time.Time) (_ []model.Achievement, err error) {
+	ctx, end := startSpan(ctx, "berakService.Add")
+	defer func() { end(err) }()
+
 	if date.IsZero() {
 		err := s.repo.Add(ctx)
 		if err != nil {
-			return fmt.Errorf("add poop: %w", err)
+			return nil, fmt.Errorf("add poop: %w", err)
+		}
+	} else {
+		err := s.repo.AddWithDate(ctx, date)
+		if err != nil {
+			return nil, fmt.Errorf("add poop with time: %w", err)
+		}
+	}
+	s.notifyChanged()
+	unlocked, err := s.EvaluateAchievements(ctx)
+	if err != nil {
+		return unlocked, fmt.Errorf("%w: %w", ErrEvaluateAchievements, err)
+	}
+	return unlocked, nil
+}
+
+// GetTimestampsBetween returns every 💩 from the start of from until the end
+// of to. A zero from or to leaves that side open.
+func (s *berakService) GetTimestampsBetween(ctx context.Context, from, to time.Time) (_ []time.Time, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetTimestampsBetween")
+	defer func() { end(err) }()
+
+	timestamps, err := s.repo.GetTimestamps(ctx, s.offset.Load().String())
+	if err != nil {
+		return nil, fmt.Errorf("get timestamps: %w", err)
+	}
+	data := make([]time.Time, 0, len(timestamps))
+	for _, t := range timestamps {
+		day := helper.TruncateToDay(t)
+		if (!from.IsZero() && day.Before(from)) || (!to.IsZero() && day.After(to)) {
+			continue
 		}
+		data = append(data, t)
+	}
+	return data, nil
+}
+
+// Version returns a value that changes whenever the 💩s or goals do, telling
+// their writes apart from those of the other data kept in the same database.
+func (s *berakService) Version(ctx context.Context) (_ string, err error) {
+	ctx, end := startSpan(ctx, "berakService.Version")
+	defer func() { end(err) }()
+
+	n, err := s.repo.Count(ctx)
+	if err != nil {
+		return "", fmt.Errorf("count: %w", err)
+	}
+	last, err := s.repo.GetLastDataTimestamp(ctx, s.offset.Load().String())
+	if err != nil {
+		return "", fmt.Errorf("get last data timestamp: %w", err)
+	}
+	goals, err := s.repo.GetGoals(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get goals: %w", err)
+	}
+	return fmt.Sprintf("%d %s %v", n, last.Format(time.RFC3339), goals), nil
+}
+
+// Swapped returns a channel that's closed once the database is swapped for
+// another one, e.g. by a restore. Without a database, it's nil and never is.
+func (s *berakService) Swapped() <-chan struct{} {
+	if s.db == nil {
 		return nil
 	}
-	err := s.repo.AddWithDate(ctx, date)
+	return s.db.Swapped()
+}
+
+func (s *berakService) CurrentTime() time.Time {
+	return s.offset.Load().Apply(time.Now().UTC())
+}
+
+func (s *berakService) GetGoalsProgress(ctx context.Context) (_ []model.GoalProgress, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetGoalsProgress")
+	defer func() { end(err) }()
+
+	currentPoopStreak, err := s.repo.GetCurrentStreak(ctx, s.offset.Load().String())
+	if err != nil {
+		return nil, fmt.Errorf("get current poop streak: %w", err)
+	}
+	return s.getGoalsProgress(ctx, currentPoopStreak)
+}
+
+func (s *berakService) getGoalsProgress(ctx context.Context, currentStreak model.PoopStreak) ([]model.GoalProgress, error) {
+	goals, err := s.repo.GetGoals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get goals: %w", err)
+	}
+	return s.progress(ctx, goals, currentStreak)
+}
+
+// progress evaluates goals, only getting the counts of this month if one of
+// them needs them.
+func (s *berakService) progress(ctx context.Context, goals []model.Goal, currentStreak model.PoopStreak) ([]model.GoalProgress, error) {
+	if len(goals) == 0 {
+		return nil, nil
+	}
+
+	var todayCount, monthCount int
+	if slices.ContainsFunc(goals, func(g model.Goal) bool { return g.Kind != model.GoalKindStreak }) {
+		now := s.CurrentTime()
+		dailyData, err := s.repo.GetDailyByMonthAndYear(ctx, uint64(now.Year()), uint64(now.Month()), s.offset.Load().String())
+		if err != nil {
+			return nil, fmt.Errorf("get daily data: %w", err)
+		}
+		for _, d := range dailyData {
+			if d.Period == now.Day() {
+				todayCount = d.Count
+			}
+			monthCount += d.Count
+		}
+	}
+
+	progress := make([]model.GoalProgress, 0, len(goals))
+	for _, g := range goals {
+		p := model.GoalProgress{Goal: g}
+		switch g.Kind {
+		case model.GoalKindDaily:
+			p.Current = todayCount
+		case model.GoalKindMonthly:
+			p.Current = monthCount
+		case model.GoalKindStreak:
+			p.Current = currentStreak.DayCount
+		}
+		progress = append(progress, p)
+	}
+
+	return progress, nil
+}
+
+func (s *berakService) GetGoalProgress(ctx context.Context, id int64) (_ model.GoalProgress, err error) {
+	ctx, end := startSpan(ctx, "berakService.GetGoalProgress")
+	defer func() { end(err) }()
+
+	g, err := s.repo.GetGoal(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.GoalProgress{}, ErrGoalNotFound
+	}
+	if err != nil {
+		return model.GoalProgress{}, fmt.Errorf("get goal: %w", err)
+	}
+	var currentPoopStreak model.PoopStreak
+	if g.Kind == model.GoalKindStreak {
+		currentPoopStreak, err = s.repo.GetCurrentStreak(ctx, s.offset.Load().String())
+		if err != nil {
+			return model.GoalProgress{}, fmt.Errorf("get current poop streak: %w", err)
+		}
+	}
+	progress, err := s.progress(ctx, []model.Goal{g}, currentPoopStreak)
+	if err != nil {
+		return model.GoalProgress{}, err
+	}
+	return progress[0], nil
+}
+
+func (s *berakService) CreateGoal(ctx context.Context, kind model.GoalKind, target int) (_ model.Goal, err error) {
+	ctx, end := startSpan(ctx, "berakService.CreateGoal")
+	defer func() { end(err) }()
+
+	if err := validateGoal(kind, target); err != nil {
+		return model.Goal{}, err
+	}
+	g, err := s.repo.AddGoal(ctx, kind, target)
+	if err != nil {
+		return model.Goal{}, fmt.Errorf("add goal: %w", err)
+	}
+	s.notifyChanged()
+	return g, nil
+}
+
+func (s *berakService) UpdateGoal(ctx context.Context, id int64, kind model.GoalKind, target int) (_ model.Goal, err error) {
+	ctx, end := startSpan(ctx, "berakService.UpdateGoal")
+	defer func() { end(err) }()
+
+	if err := validateGoal(kind, target); err != nil {
+		return model.Goal{}, err
+	}
+	g, err := s.repo.UpdateGoal(ctx, id, kind, target)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Goal{}, ErrGoalNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("add poop with time: %w", err)
+		return model.Goal{}, fmt.Errorf("update goal: %w", err)
+	}
+	s.notifyChanged()
+	return g, nil
+}
+
+func (s *berakService) DeleteGoal(ctx context.Context, id int64) (err error) {
+	ctx, end := startSpan(ctx, "berakService.DeleteGoal")
+	defer func() { end(err) }()
+
+	err = s.repo.DeleteGoal(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrGoalNotFound
 	}
+	if err != nil {
+		return fmt.Errorf("delete goal: %w", err)
+	}
+	s.notifyChanged()
 	return nil
 }
 
-func (s *berakService) CurrentTime() time.Time {
-	return s.offset.Apply(time.Now().UTC())
+func validateGoal(kind model.GoalKind, target int) error {
+	if !kind.IsValid() {
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidGoal, kind)
+	}
+	if target < 1 {
+		return fmt.Errorf("%w: target must be at least 1", ErrInvalidGoal)
+	}
+	return nil
 }
@@ -0,0 +1,532 @@
+package berak
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/model"
+)
+
+// newTestService returns a service backed by a memory repository holding a 💩
+// at every one of times, which are in the time zone of testOffset.
+func newTestService(t *testing.T, times ...time.Time) (*berakService, Repository) {
+	t.Helper()
+	offset, err := helper.NewOffset(testOffset)
+	if err != nil {
+		t.Fatalf("offset: %v", err)
+	}
+	repo := NewMemoryRepo()
+	for _, ts := range times {
+		seed(t, repo, []time.Time{ts.Add(-7 * time.Hour)})
+	}
+	return NewService(repo, nil, offset), repo
+}
+
+// daysAgo returns hour o'clock n days before today, in the time zone of
+// testOffset.
+func daysAgo(n, hour int) time.Time {
+	today := helper.TruncateToDay(time.Now().UTC().Add(7 * time.Hour))
+	return today.AddDate(0, 0, -n).Add(time.Duration(hour) * time.Hour)
+}
+
+// counts returns the counts of data, checking that there's one for every
+// period starting at 1.
+func counts(t *testing.T, data []model.AggData) []int {
+	t.Helper()
+	c := make([]int, len(data))
+	for i, d := range data {
+		if d.Period != i+1 {
+			t.Fatalf("period #%d = %d, want %d", i, d.Period, i+1)
+		}
+		c[i] = d.Count
+	}
+	return c
+}
+
+func TestGetMonthly(t *testing.T) {
+	now := local(2026, 4, 15, 12)
+	tests := []struct {
+		name  string
+		poops []time.Time
+		year  uint64
+		want  []int
+	}{
+		{
+			name: "no data",
+			year: 2026,
+			want: []int{0, 0, 0, 0},
+		},
+		{
+			name:  "current year until the current month",
+			poops: []time.Time{local(2026, 2, 1, 8), local(2026, 2, 28, 8), local(2026, 4, 15, 8)},
+			year:  2026,
+			want:  []int{0, 2, 0, 1},
+		},
+		{
+			name:  "past year",
+			poops: []time.Time{local(2025, 3, 1, 8), local(2025, 12, 31, 23), local(2026, 1, 1, 0)},
+			year:  2025,
+			want:  []int{0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(t, tt.poops...)
+			got, err := svc.GetMonthly(context.Background(), now, tt.year)
+			if err != nil {
+				t.Fatalf("GetMonthly: %v", err)
+			}
+			if got.Year != int(tt.year) || !got.CurrentTime.Equal(now) {
+				t.Errorf("year, current time = %d, %v, want %d, %v", got.Year, got.CurrentTime, tt.year, now)
+			}
+			if c := counts(t, got.Data); !reflect.DeepEqual(c, tt.want) {
+				t.Errorf("counts = %v, want %v", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDaily(t *testing.T) {
+	now := local(2026, 4, 5, 12)
+	tests := []struct {
+		name        string
+		poops       []time.Time
+		year, month uint64
+		want        []int
+	}{
+		{
+			name:  "no data",
+			year:  2026,
+			month: 4,
+			want:  []int{0, 0, 0, 0, 0},
+		},
+		{
+			name:  "current month until today",
+			poops: []time.Time{local(2026, 4, 2, 8), local(2026, 4, 2, 20), local(2026, 4, 5, 8), local(2026, 3, 31, 8)},
+			year:  2026,
+			month: 4,
+			want:  []int{0, 2, 0, 0, 1},
+		},
+		{
+			name:  "past month of the current year",
+			poops: []time.Time{local(2026, 1, 31, 8)},
+			year:  2026,
+			month: 1,
+			want:  append(make([]int, 30), 1),
+		},
+		{
+			name:  "february",
+			poops: []time.Time{local(2025, 2, 1, 8)},
+			year:  2025,
+			month: 2,
+			want:  append([]int{1}, make([]int, 27)...),
+		},
+		{
+			name:  "february of a leap year",
+			poops: []time.Time{local(2024, 2, 29, 8)},
+			year:  2024,
+			month: 2,
+			want:  append(make([]int, 28), 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(t, tt.poops...)
+			got, err := svc.GetDaily(context.Background(), now, tt.year, tt.month)
+			if err != nil {
+				t.Fatalf("GetDaily: %v", err)
+			}
+			if c := counts(t, got.Data); !reflect.DeepEqual(c, tt.want) {
+				t.Errorf("counts = %v, want %v", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetYearlySummaries(t *testing.T) {
+	now := local(2026, 4, 5, 12)
+	tests := []struct {
+		name  string
+		poops []time.Time
+		want  []model.YearSummary
+	}{
+		{
+			name: "no data",
+		},
+		{
+			name:  "years without data until the current year",
+			poops: []time.Time{local(2023, 5, 1, 8), local(2023, 5, 2, 8), local(2025, 1, 1, 8)},
+			want: []model.YearSummary{
+				{Year: 2023, Months: [12]int{4: 2}, Total: 2, LongestPoopStreak: model.PoopStreak{StartDate: date(2023, 5, 1), EndDate: date(2023, 5, 2), DayCount: 2, PoopCount: 2}},
+				{Year: 2024},
+				{Year: 2025, Months: [12]int{1}, Total: 1, LongestPoopStreak: model.PoopStreak{StartDate: date(2025, 1, 1), EndDate: date(2025, 1, 1), DayCount: 1, PoopCount: 1}},
+				{Year: 2026},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(t, tt.poops...)
+			expect(t, "GetYearlySummaries", tt.want)(svc.GetYearlySummaries(context.Background(), now))
+		})
+	}
+}
+
+func TestGetStatistics(t *testing.T) {
+	day := func(n int) time.Time { return daysAgo(n, 0) }
+	tests := []struct {
+		name      string
+		poops     []time.Time
+		goals     []model.GoalKind
+		want      model.Statistics
+		wantGoals []int
+	}{
+		{
+			name: "no data",
+		},
+		{
+			name:  "current streak",
+			poops: []time.Time{daysAgo(10, 8), daysAgo(3, 8), daysAgo(2, 8), daysAgo(2, 20), daysAgo(1, 8)},
+			goals: []model.GoalKind{model.GoalKindDaily, model.GoalKindStreak},
+			want: model.Statistics{
+				LastPoopAt:            daysAgo(1, 8),
+				LongestDayWithoutPoop: model.LongestDayWithoutPoop{StartTime: daysAgo(10, 8), EndTime: daysAgo(3, 8)},
+				OngoingDrought:        model.LongestDayWithoutPoop{StartTime: daysAgo(1, 8), Ongoing: true},
+				LongestPoopStreak:     model.PoopStreak{StartDate: day(3), EndDate: day(1), DayCount: 3, PoopCount: 4},
+				CurrentStreak:         model.PoopStreak{StartDate: day(3), EndDate: day(1), DayCount: 3, PoopCount: 4},
+				MostPoopInADay:        model.MostPoopInADate{Year: day(2).Year(), Month: int(day(2).Month()), Day: day(2).Day(), Count: 2},
+				NextPoop:              model.Forecast{SampleSize: 4},
+			},
+			wantGoals: []int{0, 3},
+		},
+		{
+			name:  "ongoing drought",
+			poops: []time.Time{daysAgo(20, 8), daysAgo(19, 8)},
+			want: model.Statistics{
+				LastPoopAt:            daysAgo(19, 8),
+				LongestDayWithoutPoop: model.LongestDayWithoutPoop{StartTime: daysAgo(19, 8), Ongoing: true},
+				OngoingDrought:        model.LongestDayWithoutPoop{StartTime: daysAgo(19, 8), Ongoing: true},
+				LongestPoopStreak:     model.PoopStreak{StartDate: day(20), EndDate: day(19), DayCount: 2, PoopCount: 2},
+				MostPoopInADay:        model.MostPoopInADate{Year: day(19).Year(), Month: int(day(19).Month()), Day: day(19).Day(), Count: 1},
+				NextPoop:              model.Forecast{SampleSize: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc, repo := newTestService(t, tt.poops...)
+			for _, kind := range tt.goals {
+				_, err := repo.AddGoal(ctx, kind, 1)
+				if err != nil {
+					t.Fatalf("AddGoal: %v", err)
+				}
+			}
+
+			got, err := svc.GetStatistics(ctx)
+			if err != nil {
+				t.Fatalf("GetStatistics: %v", err)
+			}
+			var goals []int
+			for _, g := range got.Goals {
+				goals = append(goals, g.Current)
+			}
+			if !reflect.DeepEqual(goals, tt.wantGoals) {
+				t.Errorf("goals = %v, want %v", goals, tt.wantGoals)
+			}
+			// ongoing droughts end now, the month may be split by the days
+			// used and the forecast has its own test.
+			got.Goals = nil
+			if got.OngoingDrought.Ongoing {
+				got.OngoingDrought.EndTime = time.Time{}
+			}
+			if got.LongestDayWithoutPoop.Ongoing {
+				got.LongestDayWithoutPoop.EndTime = time.Time{}
+			}
+			got.MostPoopInAMonth = model.MostPoopInADate{}
+			got.NextPoop = model.Forecast{SampleSize: got.NextPoop.SampleSize}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetStatistics =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetGoalProgress(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestService(t, daysAgo(2, 8), daysAgo(1, 8), daysAgo(0, 0), daysAgo(0, 0))
+	streak, err := repo.AddGoal(ctx, model.GoalKindStreak, 30)
+	if err != nil {
+		t.Fatalf("AddGoal: %v", err)
+	}
+	daily, err := repo.AddGoal(ctx, model.GoalKindDaily, 1)
+	if err != nil {
+		t.Fatalf("AddGoal: %v", err)
+	}
+
+	tests := []struct {
+		goal model.Goal
+		want int
+	}{
+		{streak, 3},
+		{daily, 2},
+	}
+	for _, tt := range tests {
+		got, err := svc.GetGoalProgress(ctx, tt.goal.ID)
+		if err != nil {
+			t.Fatalf("GetGoalProgress(%d): %v", tt.goal.ID, err)
+		}
+		if got.Goal != tt.goal || got.Current != tt.want {
+			t.Errorf("GetGoalProgress(%d) = %+v, want %+v at %d", tt.goal.ID, got, tt.goal, tt.want)
+		}
+	}
+
+	_, err = svc.GetGoalProgress(ctx, daily.ID+1)
+	if !errors.Is(err, ErrGoalNotFound) {
+		t.Errorf("GetGoalProgress of an unknown goal: %v, want %v", err, ErrGoalNotFound)
+	}
+}
+
+func TestGetRecords(t *testing.T) {
+	poops := []time.Time{
+		local(2020, 1, 1, 8), local(2020, 1, 1, 12), local(2020, 1, 1, 18),
+		local(2020, 1, 2, 8), local(2020, 1, 2, 20),
+		local(2020, 1, 3, 9),
+		local(2020, 1, 10, 9), local(2020, 1, 10, 10),
+		local(2020, 2, 1, 9),
+	}
+	ongoing := model.LongestDayWithoutPoop{Rank: 1, StartTime: local(2020, 2, 1, 9), Ongoing: true}
+	tests := []struct {
+		name string
+		n    int
+		want model.Records
+	}{
+		{
+			name: "top 3 with ties",
+			n:    3,
+			want: model.Records{
+				LongestPoopStreaks: []model.PoopStreak{
+					{Rank: 1, StartDate: date(2020, 1, 1), EndDate: date(2020, 1, 3), DayCount: 3, PoopCount: 6},
+					{Rank: 2, StartDate: date(2020, 2, 1), EndDate: date(2020, 2, 1), DayCount: 1, PoopCount: 1},
+					{Rank: 2, StartDate: date(2020, 1, 10), EndDate: date(2020, 1, 10), DayCount: 1, PoopCount: 2},
+				},
+				LongestDaysWithoutPoop: []model.LongestDayWithoutPoop{
+					ongoing,
+					{Rank: 2, StartTime: local(2020, 1, 10, 10), EndTime: local(2020, 2, 1, 9)},
+					{Rank: 3, StartTime: local(2020, 1, 3, 9), EndTime: local(2020, 1, 10, 9)},
+				},
+				MostPoopDays: []model.MostPoopInADate{
+					{Rank: 1, Year: 2020, Month: 1, Day: 1, Count: 3},
+					{Rank: 2, Year: 2020, Month: 1, Day: 10, Count: 2},
+					{Rank: 2, Year: 2020, Month: 1, Day: 2, Count: 2},
+				},
+				MostPoopMonths: []model.MostPoopInADate{
+					{Rank: 1, Year: 2020, Month: 1, Count: 8},
+					{Rank: 2, Year: 2020, Month: 2, Count: 1},
+				},
+			},
+		},
+		{
+			name: "ongoing drought pushes out the rest",
+			n:    1,
+			want: model.Records{
+				LongestPoopStreaks: []model.PoopStreak{
+					{Rank: 1, StartDate: date(2020, 1, 1), EndDate: date(2020, 1, 3), DayCount: 3, PoopCount: 6},
+				},
+				LongestDaysWithoutPoop: []model.LongestDayWithoutPoop{ongoing},
+				MostPoopDays: []model.MostPoopInADate{
+					{Rank: 1, Year: 2020, Month: 1, Day: 1, Count: 3},
+				},
+				MostPoopMonths: []model.MostPoopInADate{
+					{Rank: 1, Year: 2020, Month: 1, Count: 8},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(t, poops...)
+			got, err := svc.GetRecords(context.Background(), tt.n)
+			if err != nil {
+				t.Fatalf("GetRecords: %v", err)
+			}
+			for i := range got.LongestDaysWithoutPoop {
+				if got.LongestDaysWithoutPoop[i].Ongoing {
+					got.LongestDaysWithoutPoop[i].EndTime = time.Time{}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRecords =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsOngoingDroughtRecord(t *testing.T) {
+	tests := []struct {
+		name  string
+		poops []time.Time
+		want  bool
+	}{
+		{name: "no data"},
+		{name: "single poop", poops: []time.Time{daysAgo(1, 8)}, want: true},
+		{name: "shorter than a recorded gap", poops: []time.Time{daysAgo(30, 8), daysAgo(2, 8)}},
+		{name: "longer than every recorded gap", poops: []time.Time{daysAgo(30, 8), daysAgo(29, 8)}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestService(t, tt.poops...)
+			expect(t, "IsOngoingDroughtRecord", tt.want)(svc.IsOngoingDroughtRecord(context.Background()))
+		})
+	}
+}
+
+// countingRepo counts the full scans of the log.
+type countingRepo struct {
+	Repository
+	scans int
+}
+
+func (r *countingRepo) GetTimestamps(ctx context.Context, offset string) ([]time.Time, error) {
+	r.scans++
+	return r.Repository.GetTimestamps(ctx, offset)
+}
+
+func TestGetAnomaliesCached(t *testing.T) {
+	ctx := context.Background()
+	var poops []time.Time
+	for i := 40; i > 0; i-- {
+		poops = append(poops, daysAgo(i, 8))
+	}
+	// ends the longest gap, flagged as one.
+	poops = append(poops, daysAgo(0, 20))
+	svc, repo := newTestService(t, poops...)
+	counting := &countingRepo{Repository: repo}
+	svc.repo = counting
+
+	first, err := svc.GetAnomalies(ctx)
+	if err != nil {
+		t.Fatalf("GetAnomalies: %v", err)
+	}
+	if len(first) == 0 {
+		t.Fatal("GetAnomalies found no anomaly")
+	}
+	first[0] = model.Anomaly{}
+	second, err := svc.GetAnomalies(ctx)
+	if err != nil {
+		t.Fatalf("GetAnomalies: %v", err)
+	}
+	if counting.scans != 1 {
+		t.Errorf("scans after two calls = %d, want 1", counting.scans)
+	}
+	if second[0].Kind == "" {
+		t.Error("changing the returned anomalies changed the cached ones")
+	}
+
+	_, err = svc.Add(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	_, err = svc.GetAnomalies(ctx)
+	if err != nil {
+		t.Fatalf("GetAnomalies: %v", err)
+	}
+	if counting.scans != 2 {
+		t.Errorf("scans after a write = %d, want 2", counting.scans)
+	}
+}
+
+func TestGetStatisticsForecastCached(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestService(t, daysAgo(3, 8), daysAgo(2, 8), daysAgo(1, 8))
+	counting := &countingRepo{Repository: repo}
+	svc.repo = counting
+
+	for range 3 {
+		_, err := svc.GetStatistics(ctx)
+		if err != nil {
+			t.Fatalf("GetStatistics: %v", err)
+		}
+	}
+	if counting.scans != 1 {
+		t.Errorf("scans after three calls = %d, want 1", counting.scans)
+	}
+
+	err := svc.DeleteLast(ctx)
+	if err != nil {
+		t.Fatalf("DeleteLast: %v", err)
+	}
+	stats, err := svc.GetStatistics(ctx)
+	if err != nil {
+		t.Fatalf("GetStatistics: %v", err)
+	}
+	if counting.scans != 2 {
+		t.Errorf("scans after a write = %d, want 2", counting.scans)
+	}
+	if stats.NextPoop.SampleSize != 1 {
+		t.Errorf("forecast sample size after deleting a 💩 = %d, want 1", stats.NextPoop.SampleSize)
+	}
+}
+
+func TestForecastNextPoop(t *testing.T) {
+	start := local(2026, 1, 1, 8)
+	every := func(n int, d time.Duration) []time.Time {
+		var times []time.Time
+		for i := range n {
+			times = append(times, start.Add(time.Duration(i)*d))
+		}
+		return times
+	}
+	tests := []struct {
+		name       string
+		timestamps []time.Time
+		want       model.Forecast
+	}{
+		{
+			name:       "not enough data",
+			timestamps: every(1, time.Hour),
+		},
+		{
+			name:       "regular",
+			timestamps: every(4, 24*time.Hour),
+			want: model.Forecast{
+				Expected:   start.AddDate(0, 0, 4),
+				From:       start.AddDate(0, 0, 4),
+				To:         start.AddDate(0, 0, 4),
+				SampleSize: 3,
+			},
+		},
+		{
+			name:       "quartiles",
+			timestamps: []time.Time{start, start.Add(time.Hour), start.Add(3 * time.Hour), start.Add(6 * time.Hour), start.Add(10 * time.Hour)},
+			want: model.Forecast{
+				Expected:   start.Add(10*time.Hour + 150*time.Minute),
+				From:       start.Add(10*time.Hour + 105*time.Minute),
+				To:         start.Add(10*time.Hour + 195*time.Minute),
+				SampleSize: 4,
+			},
+		},
+		{
+			name:       "intervals after the same hour of day",
+			timestamps: append(every(6, 24*time.Hour), start.AddDate(0, 0, 5).Add(6*time.Hour), start.AddDate(0, 0, 6)),
+			want: model.Forecast{
+				Expected:   start.AddDate(0, 0, 7),
+				From:       start.AddDate(0, 0, 7),
+				To:         start.AddDate(0, 0, 7),
+				SampleSize: 6,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := forecastNextPoop(tt.timestamps)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("forecastNextPoop = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,632 @@
+package berak
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thansetan/berak/db"
+	"github.com/thansetan/berak/model"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+type sqliteRepository struct {
+	db *db.Conn
+}
+
+func NewSQLiteRepo(db *db.Conn) *sqliteRepository {
+	return &sqliteRepository{db}
+}
+
+func (r *sqliteRepository) Add(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+	INSERT INTO berak DEFAULT VALUES`)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *sqliteRepository) AddWithDate(ctx context.Context, t time.Time) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO berak(timestamp) VALUES(?)", t)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *sqliteRepository) DeleteLast(ctx context.Context) error {
+	res, err := r.db.ExecContext(ctx, `
+	DELETE FROM berak WHERE id = (SELECT MAX(id) FROM berak)`)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n > 1 {
+		return fmt.Errorf("more than 1 data deleted: %d", n)
+	}
+
+	return nil
+}
+
+func (r *sqliteRepository) GetMonthlyByYear(ctx context.Context, year uint64, offset string) ([]model.AggData, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			id,
+			DATETIME(timestamp, ?) timestamp
+		FROM berak
+	)
+	SELECT
+		strftime('%m', timestamp) month,
+		COUNT(id)
+	FROM timestamp_with_offset
+	WHERE strftime('%Y', timestamp) = ?
+	GROUP BY month
+	ORDER BY month;`, offset, fmt.Sprintf("%04d", year))
+	if err != nil {
+		return nil, err
+	}
+
+	var data []model.AggData
+	for rows.Next() {
+		var monthlyData model.AggData
+		err = rows.Scan(&monthlyData.Period, &monthlyData.Count)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, monthlyData)
+	}
+
+	return data, nil
+}
+
+func (r *sqliteRepository) GetDailyByMonthAndYear(ctx context.Context, year, month uint64, offset string) ([]model.AggData, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			id,
+			DATETIME(timestamp, ?) timestamp
+		FROM berak
+	)
+	SELECT
+		strftime('%d', timestamp) day,
+		COUNT(id)
+	FROM timestamp_with_offset
+	WHERE strftime('%Y', timestamp) = ? AND strftime('%m', timestamp) = ?
+	GROUP BY day
+	ORDER BY day;`, offset, fmt.Sprintf("%04d", year), fmt.Sprintf("%02d", month))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var data []model.AggData
+	for rows.Next() {
+		var dailyData model.AggData
+		err = rows.Scan(&dailyData.Period, &dailyData.Count)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, dailyData)
+	}
+
+	return data, nil
+}
+
+func (r *sqliteRepository) GetLastDataTimestamp(ctx context.Context, offset string) (time.Time, error) {
+	var lastPoopTime sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			id,
+			DATETIME(timestamp, ?) timestamp
+		FROM berak
+	)
+	SELECT timestamp FROM timestamp_with_offset ORDER BY timestamp DESC LIMIT 1`, offset).Scan(&lastPoopTime)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, err
+	}
+	if !lastPoopTime.Valid {
+		return time.Time{}, nil
+	}
+	lastInsertAt, err := time.Parse(dateTimeLayout, lastPoopTime.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse lastInsertedAt: %w", err)
+	}
+
+	return lastInsertAt, nil
+}
+
+func (r *sqliteRepository) GetLongestDayWithoutPoop(ctx context.Context, offset string) (model.LongestDayWithoutPoop, error) {
+	l, err := r.GetLongestDaysWithoutPoop(ctx, offset, 1)
+	if err != nil || len(l) == 0 {
+		return model.LongestDayWithoutPoop{}, err
+	}
+
+	return l[0], nil
+}
+
+func (r *sqliteRepository) GetLongestDaysWithoutPoop(ctx context.Context, offset string, n int) ([]model.LongestDayWithoutPoop, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH gaps AS (
+		SELECT
+			DATETIME(timestamp, ?) timestamp,
+			LAG(DATETIME(timestamp, ?)) OVER (ORDER BY timestamp) prev_timestamp
+		FROM berak
+	)
+	SELECT timestamp, prev_timestamp
+	FROM gaps
+	WHERE prev_timestamp IS NOT NULL
+	ORDER BY JULIANDAY(timestamp) - JULIANDAY(prev_timestamp) DESC, timestamp DESC
+	LIMIT ?`, offset, offset, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.LongestDayWithoutPoop
+	for rows.Next() {
+		var (
+			l                  model.LongestDayWithoutPoop
+			startTime, endTime string
+		)
+		err = rows.Scan(&endTime, &startTime)
+		if err != nil {
+			return nil, err
+		}
+		l.StartTime, err = time.Parse(dateTimeLayout, startTime)
+		if err != nil {
+			return nil, fmt.Errorf("parse startTime: %w", err)
+		}
+		l.EndTime, err = time.Parse(dateTimeLayout, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("parse endTime: %w", err)
+		}
+		data = append(data, l)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *sqliteRepository) GetMostPoopInADay(ctx context.Context, offset string) (model.MostPoopInADate, error) {
+	m, err := r.GetDaysWithMostPoop(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.MostPoopInADate{}, err
+	}
+
+	return m[0], nil
+}
+
+func (r *sqliteRepository) GetDaysWithMostPoop(ctx context.Context, offset string, n int) ([]model.MostPoopInADate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH timestamp_with_offset AS (SELECT id,
+		                                      DATETIME(timestamp, ?) timestamp
+		                               FROM berak)
+		SELECT 
+				STRFTIME('%Y', timestamp) tahun,
+			   	STRFTIME('%m', timestamp) bulan,
+		       	STRFTIME('%d', timestamp) tanggal,
+		       	COUNT(id)                 jumlah
+		FROM timestamp_with_offset
+		GROUP BY tahun, bulan, tanggal
+		ORDER BY jumlah DESC, tahun DESC, bulan DESC, tanggal DESC
+		LIMIT ?`, offset, n)
+	if err != nil {
+		return nil, fmt.Errorf("fetching most poop in a day: %w", err)
+	}
+	defer rows.Close()
+
+	var data []model.MostPoopInADate
+	for rows.Next() {
+		var m model.MostPoopInADate
+		err = rows.Scan(&m.Year, &m.Month, &m.Day, &m.Count)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, m)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *sqliteRepository) GetLongestPoopStreak(ctx context.Context, offset string) (model.PoopStreak, error) {
+	m, err := r.GetLongestPoopStreaks(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.PoopStreak{}, err
+	}
+
+	return m[0], nil
+}
+
+func (r *sqliteRepository) GetLongestPoopStreaks(ctx context.Context, offset string, n int) ([]model.PoopStreak, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH poop_per_day AS (SELECT DATE(timestamp, ?) poop_date,
+                             COUNT(timestamp)            poop_count
+                      FROM berak
+                      GROUP BY poop_date),
+     grouped_poop AS (SELECT poop_date,
+                             poop_count,
+                             JULIANDAY(poop_date) - ROW_NUMBER() OVER (ORDER BY poop_date)
+                                 "group"
+                      FROM poop_per_day)
+	SELECT MIN(poop_date)   start_date,
+	       MAX(poop_date)   end_date,
+	       COUNT(poop_date) day_count,
+	       SUM(poop_count)  poop_count
+	FROM grouped_poop
+	GROUP BY "group"
+	ORDER BY day_count DESC, end_date DESC LIMIT ?`, offset, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.PoopStreak
+	for rows.Next() {
+		var (
+			startDate, endDate string
+			m                  model.PoopStreak
+		)
+		err = rows.Scan(&startDate, &endDate, &m.DayCount, &m.PoopCount)
+		if err != nil {
+			return nil, err
+		}
+		m.StartDate, err = time.Parse(dateLayout, startDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse startDate: %w", err)
+		}
+		m.EndDate, err = time.Parse(dateLayout, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse endDate: %w", err)
+		}
+		data = append(data, m)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *sqliteRepository) GetCurrentStreak(ctx context.Context, offset string) (model.PoopStreak, error) {
+	var (
+		poopStreak         model.PoopStreak
+		startDate, endDate sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx, `
+	WITH poop_per_day AS (
+	    SELECT
+	        DATE(timestamp, ?) poop_date,
+	        COUNT(timestamp) poop_count
+	    FROM berak
+	    GROUP BY poop_date
+	),
+	grouped_poops AS (
+	    SELECT
+	        poop_date,
+	        poop_count,
+	        JULIANDAY(poop_date) - ROW_NUMBER() OVER (ORDER BY poop_date) AS group_id
+	    FROM poop_per_day
+	)
+	SELECT
+	    MIN(poop_date) AS start_date,
+	    MAX(poop_date) AS end_date,
+	    COUNT(1) AS day_count,
+	    SUM(poop_count) poop_count
+	FROM grouped_poops
+	GROUP BY group_id
+	HAVING (MAX(poop_date) = DATE('now', ?)) OR (MAX(poop_date) = DATE('now', '-1 day', ?))
+	LIMIT 1
+	`, offset, offset, offset).Scan(&startDate, &endDate, &poopStreak.DayCount, &poopStreak.PoopCount)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return poopStreak, fmt.Errorf("fetching current streak: %w", err)
+	}
+	if startDate.Valid {
+		poopStreak.StartDate, err = time.Parse(dateLayout, startDate.String)
+		if err != nil {
+			return model.PoopStreak{}, fmt.Errorf("parse startDate: %w", err)
+		}
+	}
+	if endDate.Valid {
+		poopStreak.EndDate, err = time.Parse(dateLayout, endDate.String)
+		if err != nil {
+			return model.PoopStreak{}, fmt.Errorf("parse endDate: %w", err)
+		}
+	}
+
+	return poopStreak, nil
+}
+
+func (r *sqliteRepository) GetMonthWithMostPoop(ctx context.Context, offset string) (model.MostPoopInADate, error) {
+	m, err := r.GetMonthsWithMostPoop(ctx, offset, 1)
+	if err != nil || len(m) == 0 {
+		return model.MostPoopInADate{}, err
+	}
+
+	return m[0], nil
+}
+
+func (r *sqliteRepository) GetMonthsWithMostPoop(ctx context.Context, offset string, n int) ([]model.MostPoopInADate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			DATE(timestamp, ?) timestamp
+		FROM berak
+	),
+	grouped_per_year_month AS (
+		SELECT
+	        strftime('%Y', timestamp) as year,
+	        strftime('%m', timestamp) as month,
+			COUNT(1) cnt
+	    FROM timestamp_with_offset
+		GROUP BY year, month
+	)
+	SELECT
+		*
+	FROM grouped_per_year_month
+	ORDER BY cnt DESC, year DESC, month DESC LIMIT ?
+	`, offset, n)
+	if err != nil {
+		return nil, fmt.Errorf("fetching month with most poop: %w", err)
+	}
+	defer rows.Close()
+
+	var data []model.MostPoopInADate
+	for rows.Next() {
+		var m model.MostPoopInADate
+		err = rows.Scan(&m.Year, &m.Month, &m.Count)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, m)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *sqliteRepository) AddGoal(ctx context.Context, kind model.GoalKind, target int) (model.Goal, error) {
+	var g model.Goal
+	err := r.db.QueryRowContext(ctx, `
+	INSERT INTO goals(kind, target) VALUES(?, ?)
+	RETURNING id, kind, target, created_at`, kind, target).Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt)
+	if err != nil {
+		return model.Goal{}, err
+	}
+
+	return g, nil
+}
+
+func (r *sqliteRepository) GetGoals(ctx context.Context) ([]model.Goal, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT id, kind, target, created_at FROM goals ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.Goal
+	for rows.Next() {
+		var g model.Goal
+		err = rows.Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, g)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *sqliteRepository) GetGoal(ctx context.Context, id int64) (model.Goal, error) {
+	var g model.Goal
+	err := r.db.QueryRowContext(ctx, `
+	SELECT id, kind, target, created_at FROM goals WHERE id = ?`, id).Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt)
+	if err != nil {
+		return model.Goal{}, err
+	}
+
+	return g, nil
+}
+
+func (r *sqliteRepository) UpdateGoal(ctx context.Context, id int64, kind model.GoalKind, target int) (model.Goal, error) {
+	var g model.Goal
+	err := r.db.QueryRowContext(ctx, `
+	UPDATE goals SET kind = ?, target = ? WHERE id = ?
+	RETURNING id, kind, target, created_at`, kind, target, id).Scan(&g.ID, &g.Kind, &g.Target, &g.CreatedAt)
+	if err != nil {
+		return model.Goal{}, err
+	}
+
+	return g, nil
+}
+
+func (r *sqliteRepository) DeleteGoal(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `
+	DELETE FROM goals WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *sqliteRepository) Count(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+	SELECT COUNT(1) FROM berak`).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// UnlockAchievement stores the unlock time of a badge, returning false if the
+// badge was already unlocked before.
+func (r *sqliteRepository) UnlockAchievement(ctx context.Context, badgeID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+	INSERT INTO achievements(badge_id) VALUES(?) ON CONFLICT DO NOTHING`, badgeID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *sqliteRepository) GetUnlockedAchievements(ctx context.Context, offset string) (map[string]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT badge_id, DATETIME(unlocked_at, ?) FROM achievements`, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := make(map[string]time.Time)
+	for rows.Next() {
+		var badgeID, unlockedAt string
+		err = rows.Scan(&badgeID, &unlockedAt)
+		if err != nil {
+			return nil, err
+		}
+		data[badgeID], err = time.Parse(dateTimeLayout, unlockedAt)
+		if err != nil {
+			return nil, fmt.Errorf("parse unlockedAt: %w", err)
+		}
+	}
+
+	return data, rows.Err()
+}
+
+func (r *sqliteRepository) GetTimestamps(ctx context.Context, offset string) ([]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT DATETIME(timestamp, ?) FROM berak ORDER BY timestamp`, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []time.Time
+	for rows.Next() {
+		var timestamp string
+		err = rows.Scan(&timestamp)
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(dateTimeLayout, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("parse timestamp: %w", err)
+		}
+		data = append(data, t)
+	}
+
+	return data, rows.Err()
+}
+
+// GetYearlySummaries returns the monthly 💩 count of every year with data,
+// without the longest streak.
+func (r *sqliteRepository) GetYearlySummaries(ctx context.Context, offset string) ([]model.YearSummary, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH timestamp_with_offset AS (
+		SELECT
+			id,
+			DATETIME(timestamp, ?) timestamp
+		FROM berak
+	)
+	SELECT
+		strftime('%Y', timestamp) year,
+		strftime('%m', timestamp) month,
+		COUNT(id)
+	FROM timestamp_with_offset
+	GROUP BY year, month
+	ORDER BY year, month;`, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.YearSummary
+	for rows.Next() {
+		var year, month, count int
+		err = rows.Scan(&year, &month, &count)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 || data[len(data)-1].Year != year {
+			data = append(data, model.YearSummary{Year: year})
+		}
+		data[len(data)-1].Months[month-1] = count
+		data[len(data)-1].Total += count
+	}
+
+	return data, rows.Err()
+}
+
+// GetLongestPoopStreakPerYear returns the longest streak of every year with
+// data, keyed by year. Streaks crossing the new year are split at it.
+func (r *sqliteRepository) GetLongestPoopStreakPerYear(ctx context.Context, offset string) (map[int]model.PoopStreak, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	WITH poop_per_day AS (SELECT DATE(timestamp, ?) poop_date,
+                             COUNT(timestamp)            poop_count
+                      FROM berak
+                      GROUP BY poop_date),
+     grouped_poop AS (SELECT poop_date,
+                             poop_count,
+                             strftime('%Y', poop_date) year,
+                             JULIANDAY(poop_date) - ROW_NUMBER() OVER (PARTITION BY strftime('%Y', poop_date) ORDER BY poop_date)
+                                 "group"
+                      FROM poop_per_day),
+     streaks AS (SELECT year,
+                        MIN(poop_date)   start_date,
+                        MAX(poop_date)   end_date,
+                        COUNT(poop_date) day_count,
+                        SUM(poop_count)  poop_count,
+                        ROW_NUMBER() OVER (PARTITION BY year ORDER BY COUNT(poop_date) DESC, MAX(poop_date) DESC) rn
+                 FROM grouped_poop
+                 GROUP BY year, "group")
+	SELECT year, start_date, end_date, day_count, poop_count
+	FROM streaks
+	WHERE rn = 1`, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := make(map[int]model.PoopStreak)
+	for rows.Next() {
+		var (
+			year               int
+			startDate, endDate string
+			m                  model.PoopStreak
+		)
+		err = rows.Scan(&year, &startDate, &endDate, &m.DayCount, &m.PoopCount)
+		if err != nil {
+			return nil, err
+		}
+		m.StartDate, err = time.Parse(dateLayout, startDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse startDate: %w", err)
+		}
+		m.EndDate, err = time.Parse(dateLayout, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("parse endDate: %w", err)
+		}
+		data[year] = m
+	}
+
+	return data, rows.Err()
+}
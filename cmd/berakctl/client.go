@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/thansetan/berak/model"
+)
+
+type client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+	stream  *http.Client
+}
+
+func newClient(cfg config) *client {
+	return &client{
+		baseURL: cfg.URL,
+		apiKey:  cfg.APIKey,
+		http:    &http.Client{Timeout: 30 * time.Second},
+		// streams stay open, so they can't have a timeout.
+		stream: &http.Client{},
+	}
+}
+
+type monthData struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Total int `json:"total"`
+	Days  []struct {
+		Day   int `json:"day"`
+		Count int `json:"count"`
+	} `json:"days"`
+}
+
+// apiError is a non-2xx response of the server.
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server responded with %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+func (c *client) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-Api-Key", c.apiKey)
+	}
+	return req, nil
+}
+
+// checkResponse returns res if its status is 2xx, and closes it otherwise.
+func checkResponse(res *http.Response) (*http.Response, error) {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	apiErr := &apiError{StatusCode: res.StatusCode}
+	var msg struct {
+		Message string `json:"message"`
+	}
+	if json.NewDecoder(res.Body).Decode(&msg) == nil {
+		apiErr.Message = msg.Message
+	}
+	return nil, apiErr
+}
+
+// do sends body, if any, as JSON and returns the response if its status is
+// 2xx. The caller has to close its body.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := c.newRequest(ctx, method, path, query, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return checkResponse(res)
+}
+
+func (c *client) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	res, err := c.do(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	err = json.NewDecoder(res.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// Log adds a 💩 dropped at at, or now if at is zero.
+func (c *client) Log(ctx context.Context, at time.Time) error {
+	var body any
+	if !at.IsZero() {
+		body = struct {
+			Timestamp time.Time `json:"timestamp"`
+		}{at}
+	}
+	res, err := c.do(ctx, http.MethodPost, "/berak", nil, body)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Undo removes the last added 💩.
+func (c *client) Undo(ctx context.Context) error {
+	res, err := c.do(ctx, http.MethodDelete, "/berak", nil, nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Last returns the time of the last 💩 in the server's time zone, or zero if
+// there's none.
+func (c *client) Last(ctx context.Context) (time.Time, error) {
+	var data struct {
+		LastPoopTime time.Time `json:"last_poop_time"`
+	}
+	err := c.getJSON(ctx, "/last_poop", nil, &data)
+	return data.LastPoopTime, err
+}
+
+// statistics are the statistics along with the server's current time, which
+// like every time of the server is in its time zone but marked as UTC.
+type statistics struct {
+	model.Statistics
+	CurrentTime time.Time `json:"current_time"`
+}
+
+func (c *client) Stats(ctx context.Context) (statistics, error) {
+	var data statistics
+	err := c.getJSON(ctx, "/api/v1/stats", nil, &data)
+	return data, err
+}
+
+func (c *client) Month(ctx context.Context, year int, month time.Month) (monthData, error) {
+	var data monthData
+	err := c.getJSON(ctx, fmt.Sprintf("/api/v1/months/%d/%d", year, month), nil, &data)
+	return data, err
+}
+
+// Export writes the 💩s between from and to, either may be empty, as CSV or
+// JSON to w.
+func (c *client) Export(ctx context.Context, w io.Writer, from, to, format string) error {
+	q := url.Values{}
+	if from != "" {
+		q.Set("from", from)
+	}
+	if to != "" {
+		q.Set("to", to)
+	}
+	if format != "" {
+		q.Set("format", format)
+	}
+	res, err := c.do(ctx, http.MethodGet, "/api/v1/export", q, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// Events opens the server-sent events stream of the days of a month, which
+// gets an event whenever a 💩 is added or removed. The caller has to close the
+// body of the response.
+func (c *client) Events(ctx context.Context, year int, month time.Month) (*http.Response, error) {
+	q := url.Values{}
+	q.Set("period", "daily")
+	q.Set("year", strconv.Itoa(year))
+	q.Set("month", strconv.Itoa(int(month)))
+	req, err := c.newRequest(ctx, http.MethodGet, "/sse", q, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	res, err := c.stream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return checkResponse(res)
+}
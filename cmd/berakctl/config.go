@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type config struct {
+	// URL is the base URL of the server, e.g. https://berak.example.com.
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+}
+
+// defaultConfigPath is berak/berakctl.json in the user's config directory.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "berakctl.json"
+	}
+	return filepath.Join(dir, "berak", "berakctl.json")
+}
+
+// loadConfig reads the config file at path, with BERAK_URL and BERAK_API_KEY
+// taking precedence over it. A missing file is fine as long as both are set.
+func loadConfig(path string) (config, error) {
+	var cfg config
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, fmt.Errorf("read config: %w", err)
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &cfg)
+		if err != nil {
+			return cfg, fmt.Errorf("parse config %s: %w", path, err)
+		}
+	}
+
+	if v := os.Getenv("BERAK_URL"); v != "" {
+		cfg.URL = v
+	}
+	if v := os.Getenv("BERAK_API_KEY"); v != "" {
+		cfg.APIKey = v
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.URL == "" {
+		return cfg, fmt.Errorf("no server URL, set url in %s or BERAK_URL", path)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,338 @@
+// Command berakctl logs and queries 💩s on a berak server over HTTP.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/thansetan/berak/model"
+)
+
+const usage = `usage: berakctl [-config file] [-json] <command> [args]
+
+commands:
+  log [-at time]                      add a 💩, dropped now or at time
+  undo                                remove the last added 💩
+  last                                show when the last 💩 dropped
+  stats                               show the statistics
+  month YYYY-MM                       show the 💩s of every day of a month
+  export [-from date] [-to date]      write the 💩s between two dates as CSV
+  watch                               show a live dashboard
+
+The server URL and API key are read from the config file, a JSON object with
+"url" and "api_key", or from BERAK_URL and BERAK_API_KEY.
+`
+
+// atLayouts are the layouts accepted by log -at, all but RFC 3339 in the local
+// time zone.
+var atLayouts = []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", "15:04"}
+
+type cli struct {
+	client *client
+	out    io.Writer
+	json   bool
+}
+
+func main() {
+	var (
+		configPath string
+		asJSON     bool
+	)
+	flag.StringVar(&configPath, "config", defaultConfigPath(), "config file")
+	flag.BoolVar(&asJSON, "json", false, "print JSON instead of tables")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "berakctl:", err)
+		os.Exit(1)
+	}
+	c := &cli{client: newClient(cfg), out: os.Stdout, json: asJSON}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	os.Exit(c.run(ctx, flag.Arg(0), flag.Args()[1:]))
+}
+
+func (c *cli) run(ctx context.Context, cmd string, args []string) int {
+	var err error
+	switch cmd {
+	case "log":
+		err = c.log(ctx, args)
+	case "undo":
+		err = c.undo(ctx, args)
+	case "last":
+		err = c.last(ctx, args)
+	case "stats":
+		err = c.stats(ctx, args)
+	case "month":
+		err = c.month(ctx, args)
+	case "export":
+		err = c.export(ctx, args)
+	case "watch":
+		err = c.watch(ctx, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		return 2
+	}
+
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		fmt.Fprintf(os.Stderr, "usage: berakctl %s\n", usageErr)
+		return 2
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "berakctl:", err)
+		return 1
+	}
+	return 0
+}
+
+// usageError holds the usage of a command that got invalid arguments.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func noArgs(name string, args []string) error {
+	fs := newFlagSet(name)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError(name)
+	}
+	return nil
+}
+
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (c *cli) log(ctx context.Context, args []string) error {
+	fs := newFlagSet("log")
+	atStr := fs.String("at", "", `when the 💩 dropped, as RFC 3339, "YYYY-MM-DD HH:MM[:SS]" or "HH:MM" today`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("log [-at time]")
+	}
+
+	var at time.Time
+	if *atStr != "" {
+		var err error
+		at, err = parseAt(*atStr, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	err := c.client.Log(ctx, at)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(struct {
+			Logged bool `json:"logged"`
+		}{true})
+	}
+	fmt.Fprintln(c.out, "💩 logged!")
+	return nil
+}
+
+func parseAt(s string, now time.Time) (time.Time, error) {
+	for _, layout := range atLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		if layout == "15:04" {
+			y, m, d := now.Date()
+			t = time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, time.Local)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf(`invalid time %q, use RFC 3339, "YYYY-MM-DD HH:MM[:SS]" or "HH:MM"`, s)
+}
+
+func (c *cli) undo(ctx context.Context, args []string) error {
+	if err := noArgs("undo", args); err != nil {
+		return err
+	}
+	err := c.client.Undo(ctx)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(struct {
+			Removed bool `json:"removed"`
+		}{true})
+	}
+	fmt.Fprintln(c.out, "last 💩 removed!")
+	return nil
+}
+
+func (c *cli) last(ctx context.Context, args []string) error {
+	if err := noArgs("last", args); err != nil {
+		return err
+	}
+	t, err := c.client.Last(ctx)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(struct {
+			LastPoopTime time.Time `json:"last_poop_time"`
+		}{t})
+	}
+	if t.IsZero() {
+		fmt.Fprintln(c.out, "no 💩 yet")
+		return nil
+	}
+	fmt.Fprintln(c.out, formatTime(t))
+	return nil
+}
+
+// formatTime formats a time of the server, which is in its time zone but
+// marked as UTC.
+func formatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04")
+}
+
+func formatDate(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
+
+func (c *cli) stats(ctx context.Context, args []string) error {
+	if err := noArgs("stats", args); err != nil {
+		return err
+	}
+	s, err := c.client.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(s)
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	row := func(name, format string, a ...any) {
+		fmt.Fprintf(tw, "%s\t%s\n", name, fmt.Sprintf(format, a...))
+	}
+	if s.LastPoopAt.IsZero() {
+		row("last poop", "-")
+	} else {
+		row("last poop", "%s", formatTime(s.LastPoopAt))
+	}
+	if !s.OngoingDrought.IsEmpty() {
+		row("since then", "%s", s.OngoingDrought)
+	}
+	row("current streak", "%s", formatStreak(s.CurrentStreak))
+	row("longest streak", "%s", formatStreak(s.LongestPoopStreak))
+	if s.LongestDayWithoutPoop.IsEmpty() {
+		row("longest drought", "-")
+	} else {
+		row("longest drought", "%s, since %s", s.LongestDayWithoutPoop, formatTime(s.LongestDayWithoutPoop.StartTime))
+	}
+	if s.MostPoopInADay.IsEmpty() {
+		row("most in a day", "-")
+	} else {
+		row("most in a day", "%d on %04d-%02d-%02d", s.MostPoopInADay.Count, s.MostPoopInADay.Year, s.MostPoopInADay.Month, s.MostPoopInADay.Day)
+	}
+	if s.MostPoopInAMonth.IsEmpty() {
+		row("most in a month", "-")
+	} else {
+		row("most in a month", "%d in %04d-%02d", s.MostPoopInAMonth.Count, s.MostPoopInAMonth.Year, s.MostPoopInAMonth.Month)
+	}
+	if s.NextPoop.IsEmpty() {
+		row("next poop", "-")
+	} else {
+		row("next poop", "%s", s.NextPoop)
+	}
+	for _, g := range s.Goals {
+		row(fmt.Sprintf("%s goal", g.Kind), "%d/%d", g.Current, g.Target)
+	}
+	return tw.Flush()
+}
+
+func formatStreak(s model.PoopStreak) string {
+	if s.DayCount == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d days, %d 💩s (%s to %s)", s.DayCount, s.PoopCount, formatDate(s.StartDate), formatDate(s.EndDate))
+}
+
+func (c *cli) month(ctx context.Context, args []string) error {
+	fs := newFlagSet("month")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("month YYYY-MM")
+	}
+	m, err := time.Parse("2006-01", fs.Arg(0))
+	if err != nil {
+		return usageError("month YYYY-MM")
+	}
+	data, err := c.client.Month(ctx, m.Year(), m.Month())
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(data)
+	}
+
+	fmt.Fprintf(c.out, "%s %d\n", m.Month(), m.Year())
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DAY\tCOUNT\t")
+	for _, d := range data.Days {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", d.Day, d.Count, strings.Repeat("💩", d.Count))
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t\n", data.Total)
+	return tw.Flush()
+}
+
+func (c *cli) export(ctx context.Context, args []string) error {
+	fs := newFlagSet("export")
+	from := fs.String("from", "", "first day as YYYY-MM-DD")
+	to := fs.String("to", "", "last day as YYYY-MM-DD")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError("export [-from date] [-to date]")
+	}
+	format := "csv"
+	if c.json {
+		format = "json"
+	}
+	return c.client.Export(ctx, c.out, *from, *to, format)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAt(t *testing.T) {
+	now := time.Date(2025, 3, 14, 22, 30, 0, 0, time.Local)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "2025-03-01T07:15:00Z", want: time.Date(2025, 3, 1, 7, 15, 0, 0, time.UTC)},
+		{in: "2025-03-01 07:15:30", want: time.Date(2025, 3, 1, 7, 15, 30, 0, time.Local)},
+		{in: "2025-03-01 07:15", want: time.Date(2025, 3, 1, 7, 15, 0, 0, time.Local)},
+		// a time alone is of today.
+		{in: "07:15", want: time.Date(2025, 3, 14, 7, 15, 0, 0, time.Local)},
+		{in: "7:15", want: time.Date(2025, 3, 14, 7, 15, 0, 0, time.Local)},
+		{in: "25:00", wantErr: true},
+		{in: "yesterday", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseAt(tt.in, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseAt(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAt(%q): %v", tt.in, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseAt(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    int
+		body    string
+		wantErr string
+	}{
+		{name: "success", code: http.StatusCreated, body: `{"message":"💩 added!"}`},
+		{
+			name:    "message",
+			code:    http.StatusForbidden,
+			body:    `{"message":"gaboleh 😡"}`,
+			wantErr: "gaboleh 😡 (403)",
+		},
+		{
+			name:    "no JSON",
+			code:    http.StatusBadGateway,
+			body:    `<html>bad gateway</html>`,
+			wantErr: "server responded with 502 Bad Gateway",
+		},
+		{
+			name:    "empty body",
+			code:    http.StatusNotFound,
+			wantErr: "server responded with 404 Not Found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{StatusCode: tt.code, Body: io.NopCloser(strings.NewReader(tt.body))}
+			got, err := checkResponse(res)
+			if tt.wantErr == "" {
+				if err != nil || got != res {
+					t.Errorf("checkResponse = %v, %v, want the response", got, err)
+				}
+				return
+			}
+			var apiErr *apiError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("checkResponse error = %v, want an *apiError", err)
+			}
+			if apiErr.StatusCode != tt.code || err.Error() != tt.wantErr {
+				t.Errorf("checkResponse error = %q (%d), want %q (%d)", err, apiErr.StatusCode, tt.wantErr, tt.code)
+			}
+		})
+	}
+}
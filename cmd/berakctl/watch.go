@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	sparklineDays = 30
+	// eventsRetry is how long to wait before reconnecting to the events stream.
+	eventsRetry = 3 * time.Second
+)
+
+// ANSI escape sequences, the only terminal features the dashboard relies on.
+const (
+	ansiClear      = "\x1b[H\x1b[2J"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiBold       = "\x1b[1m"
+	ansiDim        = "\x1b[2m"
+	ansiRed        = "\x1b[31m"
+	ansiYellow     = "\x1b[33m"
+	ansiReset      = "\x1b[0m"
+)
+
+var sparkLevels = []rune(" ▁▂▃▄▅▆▇█")
+
+// dashboard is what watch shows.
+type dashboard struct {
+	stats statistics
+	// days holds the 💩 count of the last sparklineDays days, today last.
+	days []int
+	// fetchedAt is when stats were fetched, to keep the server's current time
+	// ticking without fetching it again.
+	fetchedAt time.Time
+
+	fetchErr  error
+	connected bool
+	streamErr error
+}
+
+func (d *dashboard) now() time.Time {
+	return d.stats.CurrentTime.Add(time.Since(d.fetchedAt))
+}
+
+// watch shows a dashboard that's updated whenever the server sends an event.
+func (c *cli) watch(ctx context.Context, args []string) error {
+	if err := noArgs("watch", args); err != nil {
+		return err
+	}
+
+	var d dashboard
+	d.fetchErr = c.fetchDashboard(ctx, &d)
+	if d.fetchErr != nil && d.fetchedAt.IsZero() {
+		return d.fetchErr
+	}
+
+	updates := make(chan struct{}, 1)
+	streamErrs := make(chan error, 1)
+	now := d.now()
+	go c.followEvents(ctx, now.Year(), now.Month(), updates, streamErrs)
+
+	fmt.Fprint(c.out, ansiHideCursor)
+	defer fmt.Fprint(c.out, ansiShowCursor)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		c.renderDashboard(&d)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-updates:
+			d.connected, d.streamErr = true, nil
+			d.fetchErr = c.fetchDashboard(ctx, &d)
+		case err := <-streamErrs:
+			d.connected, d.streamErr = false, err
+		}
+	}
+}
+
+// followEvents sends to updates whenever the server sends an event, and the
+// error to errs whenever the stream is lost, until ctx is done.
+func (c *cli) followEvents(ctx context.Context, year int, month time.Month, updates chan<- struct{}, errs chan<- error) {
+	for {
+		err := c.readEvents(ctx, year, month, updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		select {
+		case errs <- err:
+		default:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventsRetry):
+		}
+	}
+}
+
+func (c *cli) readEvents(ctx context.Context, year int, month time.Month, updates chan<- struct{}) error {
+	res, err := c.client.Events(ctx, year, month)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	s := bufio.NewScanner(res.Body)
+	// an event carries whole rendered tables.
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		// only whether something happened matters, the data is rendered HTML.
+		if !strings.HasPrefix(s.Text(), "event:") {
+			continue
+		}
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+	return s.Err()
+}
+
+// fetchDashboard fetches the statistics and the days of the sparkline into
+// d, leaving d as it was on error.
+func (c *cli) fetchDashboard(ctx context.Context, d *dashboard) error {
+	stats, err := c.client.Stats(ctx)
+	if err != nil {
+		return fmt.Errorf("get statistics: %w", err)
+	}
+	fetchedAt := time.Now()
+	today := time.Date(stats.CurrentTime.Year(), stats.CurrentTime.Month(), stats.CurrentTime.Day(), 0, 0, 0, 0, time.UTC)
+	first := today.AddDate(0, 0, -sparklineDays+1)
+
+	counts := make(map[time.Time]int, sparklineDays)
+	for m := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(today); m = m.AddDate(0, 1, 0) {
+		data, err := c.client.Month(ctx, m.Year(), m.Month())
+		if err != nil {
+			return fmt.Errorf("get %04d-%02d: %w", m.Year(), m.Month(), err)
+		}
+		for _, day := range data.Days {
+			counts[time.Date(m.Year(), m.Month(), day.Day, 0, 0, 0, 0, time.UTC)] = day.Count
+		}
+	}
+	days := make([]int, sparklineDays)
+	for i := range days {
+		days[i] = counts[first.AddDate(0, 0, i)]
+	}
+
+	d.stats, d.days, d.fetchedAt = stats, days, fetchedAt
+	return nil
+}
+
+func (c *cli) renderDashboard(d *dashboard) {
+	var b strings.Builder
+	now := d.now()
+	b.WriteString(ansiClear)
+	fmt.Fprintf(&b, "%s💩 berak%s  %s%s  %s%s\n\n", ansiBold, ansiReset, ansiDim, c.client.baseURL, now.Format("2006-01-02 15:04:05"), ansiReset)
+
+	row := func(name, value string) {
+		fmt.Fprintf(&b, "  %-16s%s\n", name, value)
+	}
+	s := d.stats
+	if s.LastPoopAt.IsZero() {
+		row("last poop", "-")
+	} else {
+		row("last poop", fmt.Sprintf("%s %s(%s ago)%s", formatTime(s.LastPoopAt), ansiYellow, formatSince(now.Sub(s.LastPoopAt)), ansiReset))
+	}
+	row("today", fmt.Sprint(d.days[len(d.days)-1]))
+	row("current streak", formatStreakDays(s.CurrentStreak.DayCount))
+	row("longest streak", formatStreakDays(s.LongestPoopStreak.DayCount))
+	if !s.NextPoop.IsEmpty() {
+		row("next poop", s.NextPoop.String())
+	}
+
+	var maxCount, total int
+	for _, n := range d.days {
+		maxCount = max(maxCount, n)
+		total += n
+	}
+	fmt.Fprintf(&b, "\n  last %d days %s(%d total, at most %d a day)%s\n", sparklineDays, ansiDim, total, maxCount, ansiReset)
+	fmt.Fprintf(&b, "  %s\n", sparkline(d.days, maxCount))
+	first := now.AddDate(0, 0, -sparklineDays+1)
+	fmt.Fprintf(&b, "  %s%-*s%s%s\n\n", ansiDim, sparklineDays-5, first.Format("01-02"), now.Format("01-02"), ansiReset)
+
+	switch {
+	case d.fetchErr != nil:
+		fmt.Fprintf(&b, "%s%v%s\n", ansiRed, d.fetchErr, ansiReset)
+	case d.streamErr != nil:
+		fmt.Fprintf(&b, "%snot receiving updates, retrying: %v%s\n", ansiRed, d.streamErr, ansiReset)
+	case !d.connected:
+		fmt.Fprintf(&b, "%sconnecting...%s\n", ansiDim, ansiReset)
+	}
+	fmt.Fprintf(&b, "%sctrl+c to quit%s\n", ansiDim, ansiReset)
+
+	io.WriteString(c.out, b.String())
+}
+
+func sparkline(days []int, maxCount int) string {
+	var b strings.Builder
+	for _, n := range days {
+		level := 0
+		if n > 0 {
+			// rounded up, so any 💩 gets at least the lowest bar.
+			level = (n*(len(sparkLevels)-1) + maxCount - 1) / maxCount
+		}
+		b.WriteRune(sparkLevels[level])
+	}
+	return b.String()
+}
+
+func formatStreakDays(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+// formatSince formats d as days, hours, minutes and seconds, leaving out the
+// leading zero units.
+func formatSince(d time.Duration) string {
+	d = max(d, 0).Truncate(time.Second)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		return fmt.Sprintf("%dd %dh %dm %ds", days, d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second)
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(fmt.Sprintf("%dh %dm %ds", d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second), "0h "), "0m ")
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/thansetan/berak/config"
+)
+
+const configUsage = `usage: berak config check [file]
+
+Validates the config file, BERAK_CONFIG when no file is given, along with the
+environment, and prints the resulting configuration with its secrets masked.
+`
+
+// runConfig runs the config subcommands, which for now is only check.
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "check" || len(args) > 2 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 2
+	}
+	path := os.Getenv(config.PathEnv)
+	if len(args) == 2 {
+		path = args[1]
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(cfg.Redacted())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "configuration is valid")
+	return 0
+}
@@ -0,0 +1,451 @@
+// Package config holds the settings of the server, read once at startup from
+// an optional JSON file and the environment.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thansetan/berak/db"
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/logging"
+	"github.com/thansetan/berak/model"
+	"github.com/thansetan/berak/ratelimit"
+	"github.com/thansetan/berak/tracing"
+)
+
+// PathEnv names the environment variable holding the path of the config file.
+const PathEnv = "BERAK_CONFIG"
+
+type Config struct {
+	// DataSourceName is the SQLite file or the postgres:// URL of the database.
+	DataSourceName string `json:"data_source_name"`
+	// Key is accepted as an admin token. It is disabled when empty.
+	Key string `json:"berak_key"`
+	// TimeOffset is added to UTC to get the local time, e.g. "+7 hours".
+	TimeOffset        string           `json:"time_offset"`
+	Port              int              `json:"port"`
+	AllowedSSEOrigins string           `json:"allowed_sse_origins"`
+	BaseURL           string           `json:"base_url"`
+	Visibility        model.Visibility `json:"visibility"`
+	ShareSecret       string           `json:"share_secret"`
+	Backup            Backup           `json:"backup"`
+	RateLimit         RateLimit        `json:"rate_limit"`
+	Tracing           Tracing          `json:"tracing"`
+	Log               Log              `json:"log"`
+	// TrustedProxies are the addresses or CIDRs of the proxies in front of
+	// the server, whose X-Forwarded-For and X-Real-Ip headers are believed.
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	offset         helper.Offset
+	trustedProxies []netip.Prefix
+}
+
+type Backup struct {
+	// Dir enables scheduled backups when set.
+	Dir        string   `json:"dir"`
+	Interval   Duration `json:"interval"`
+	Keep       int      `json:"keep"`
+	Passphrase string   `json:"passphrase"`
+}
+
+type Log struct {
+	// Format is text or json.
+	Format string `json:"format"`
+	// Sample maps paths, or path prefixes ending with a slash, to the share of
+	// their successful requests that get logged, e.g. {"/metrics": 0.1}. In the
+	// environment it's written as "/metrics=0.1,/css/=0".
+	Sample map[string]float64 `json:"sample"`
+}
+
+func parseSample(s string) (map[string]float64, error) {
+	sample := make(map[string]float64)
+	for _, pair := range strings.Split(s, ",") {
+		path, rate, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not written as path=rate", pair)
+		}
+		r, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a rate", rate)
+		}
+		sample[path] = r
+	}
+	return sample, nil
+}
+
+type Tracing struct {
+	// Exporter is where spans are sent: none, stdout or otlp, the latter
+	// configured by the standard OTEL_EXPORTER_OTLP_* variables.
+	Exporter string `json:"exporter"`
+}
+
+type RateLimit struct {
+	// Algorithm is token_bucket or sliding_window.
+	Algorithm string `json:"algorithm"`
+	// IP limits the writes per client IP.
+	IP Limit `json:"ip"`
+	// APIKey limits the writes per API key, on top of IP.
+	APIKey Limit `json:"api_key"`
+	// Routes replaces IP for some of the routes it applies to, the writes and
+	// the login, named like "POST /login". They're then counted apart from the
+	// others. In the environment it's written as
+	// "POST /login=10/1m,DELETE /berak=1/1m".
+	Routes map[string]Limit `json:"routes"`
+}
+
+// RouteLimits is Routes for the rate limiter.
+func (r RateLimit) RouteLimits() map[string]ratelimit.Limit {
+	routes := make(map[string]ratelimit.Limit, len(r.Routes))
+	for route, l := range r.Routes {
+		routes[route] = l.Limit()
+	}
+	return routes
+}
+
+func parseRoutes(s string) (map[string]Limit, error) {
+	routes := make(map[string]Limit)
+	for _, pair := range strings.Split(s, ",") {
+		route, limit, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not written as route=requests/window", pair)
+		}
+		l, err := parseLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		routes[route] = l
+	}
+	return routes, nil
+}
+
+// Limit allows Requests requests per Window. In the environment it's written
+// as "requests/window", e.g. "5/1m".
+type Limit struct {
+	Requests uint64   `json:"requests"`
+	Window   Duration `json:"window"`
+}
+
+// Limit is l for the rate limiter.
+func (l Limit) Limit() ratelimit.Limit {
+	return ratelimit.Limit{Requests: l.Requests, Window: time.Duration(l.Window)}
+}
+
+func parseLimit(s string) (Limit, error) {
+	requests, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%q is not written as requests/window, e.g. \"5/1m\"", s)
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(requests), 10, 64)
+	if err != nil {
+		return Limit{}, fmt.Errorf("%q is not a number of requests", requests)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil {
+		return Limit{}, err
+	}
+	return Limit{n, Duration(d)}, nil
+}
+
+// Duration is a time.Duration written as a string like "24h" in JSON.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return fmt.Errorf("duration must be a string like \"24h\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func defaults() Config {
+	return Config{
+		Port:       6969,
+		Visibility: model.VisibilityPublic,
+		Backup: Backup{
+			Interval: Duration(24 * time.Hour),
+			Keep:     7,
+		},
+		RateLimit: RateLimit{
+			Algorithm: ratelimit.AlgorithmSlidingWindow,
+			IP:        Limit{5, Duration(time.Minute)},
+			APIKey:    Limit{1, Duration(time.Minute)},
+		},
+		Tracing: Tracing{Exporter: tracing.ExporterNone},
+		Log:     Log{Format: logging.FormatText},
+	}
+}
+
+// Load reads the config file at path, if any, applies the environment on top
+// of it and validates the result. Every invalid setting is reported at once.
+func Load(path string) (Config, error) {
+	cfg := defaults()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("read config: %w", err)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+		if err != nil {
+			return cfg, fmt.Errorf("parse config %s: %w", path, err)
+		}
+	}
+
+	errs := cfg.applyEnv()
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return cfg, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides the settings whose environment variable is set and not
+// empty.
+func (c *Config) applyEnv() []error {
+	var errs []error
+	str := func(name string, dst *string) {
+		if v := os.Getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	str("DATA_SOURCE_NAME", &c.DataSourceName)
+	str("BERAK_KEY", &c.Key)
+	str("TIME_OFFSET", &c.TimeOffset)
+	str("ALLOWED_SSE_ORIGINS", &c.AllowedSSEOrigins)
+	str("BASE_URL", &c.BaseURL)
+	str("SHARE_SECRET", &c.ShareSecret)
+	str("BACKUP_DIR", &c.Backup.Dir)
+	str("BACKUP_PASSPHRASE", &c.Backup.Passphrase)
+	str("TRACING_EXPORTER", &c.Tracing.Exporter)
+	str("LOG_FORMAT", &c.Log.Format)
+	str("RATE_LIMIT_ALGORITHM", &c.RateLimit.Algorithm)
+	if v := os.Getenv("RATE_LIMIT_ROUTES"); v != "" {
+		routes, err := parseRoutes(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("RATE_LIMIT_ROUTES: %w", err))
+		} else {
+			c.RateLimit.Routes = routes
+		}
+	}
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		c.TrustedProxies = strings.Split(v, ",")
+	}
+	if v := os.Getenv("LOG_SAMPLE"); v != "" {
+		sample, err := parseSample(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("LOG_SAMPLE: %w", err))
+		} else {
+			c.Log.Sample = sample
+		}
+	}
+	if v := os.Getenv("VISIBILITY"); v != "" {
+		c.Visibility = model.Visibility(v)
+	}
+
+	integer := func(name string, dst *int) {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+	integer("PORT", &c.Port)
+	integer("BACKUP_KEEP", &c.Backup.Keep)
+	if v := os.Getenv("BACKUP_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("BACKUP_INTERVAL: %w", err))
+		} else {
+			c.Backup.Interval = Duration(d)
+		}
+	}
+	limit := func(name string, dst *Limit) {
+		if v := os.Getenv(name); v != "" {
+			l, err := parseLimit(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			*dst = l
+		}
+	}
+	limit("RATE_LIMIT_IP", &c.RateLimit.IP)
+	limit("RATE_LIMIT_API_KEY", &c.RateLimit.APIKey)
+	return errs
+}
+
+// validate normalizes the settings and returns an error for each invalid one,
+// naming both its key in the file and its environment variable.
+func (c *Config) validate() []error {
+	var errs []error
+	invalid := func(key, env, format string, a ...any) {
+		errs = append(errs, fmt.Errorf("%s (%s): %s", key, env, fmt.Sprintf(format, a...)))
+	}
+
+	if c.DataSourceName == "" {
+		invalid("data_source_name", "DATA_SOURCE_NAME", "is required")
+	}
+
+	offset, err := helper.NewOffset(c.TimeOffset)
+	if err != nil {
+		invalid("time_offset", "TIME_OFFSET", `%v, use e.g. "+7 hours"`, err)
+	}
+	c.offset = offset
+
+	if c.Port < 1 || c.Port > 65535 {
+		invalid("port", "PORT", "%d is not between 1 and 65535", c.Port)
+	}
+
+	c.BaseURL = strings.TrimRight(c.BaseURL, "/")
+	if c.BaseURL != "" {
+		u, err := url.Parse(c.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("base_url", "BASE_URL", "%q is not an http or https URL", c.BaseURL)
+		}
+	}
+
+	c.Visibility = model.Visibility(strings.ToLower(strings.TrimSpace(string(c.Visibility))))
+	if !c.Visibility.IsValid() {
+		invalid("visibility", "VISIBILITY", "%q is not one of public, coarse or private", c.Visibility)
+	}
+
+	if c.Backup.Interval <= 0 {
+		invalid("backup.interval", "BACKUP_INTERVAL", "must be positive")
+	}
+	if c.Backup.Keep < 1 {
+		invalid("backup.keep", "BACKUP_KEEP", "at least one backup has to be kept")
+	}
+	c.Tracing.Exporter = strings.ToLower(strings.TrimSpace(c.Tracing.Exporter))
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		invalid("tracing.exporter", "TRACING_EXPORTER", "%q is not one of none, stdout or otlp", c.Tracing.Exporter)
+	}
+
+	c.Log.Format = strings.ToLower(strings.TrimSpace(c.Log.Format))
+	if c.Log.Format != logging.FormatText && c.Log.Format != logging.FormatJSON {
+		invalid("log.format", "LOG_FORMAT", "%q is not one of text or json", c.Log.Format)
+	}
+	for path, rate := range c.Log.Sample {
+		if !strings.HasPrefix(path, "/") || rate < 0 || rate > 1 {
+			invalid("log.sample", "LOG_SAMPLE", "%s=%v isn't a path with a rate between 0 and 1", path, rate)
+		}
+	}
+
+	c.RateLimit.Algorithm = strings.ToLower(strings.TrimSpace(c.RateLimit.Algorithm))
+	if c.RateLimit.Algorithm != ratelimit.AlgorithmTokenBucket && c.RateLimit.Algorithm != ratelimit.AlgorithmSlidingWindow {
+		invalid("rate_limit.algorithm", "RATE_LIMIT_ALGORITHM", "%q is not one of token_bucket or sliding_window", c.RateLimit.Algorithm)
+	}
+	type namedLimit struct {
+		key, env string
+		limit    Limit
+	}
+	limits := []namedLimit{
+		{"rate_limit.ip", "RATE_LIMIT_IP", c.RateLimit.IP},
+		{"rate_limit.api_key", "RATE_LIMIT_API_KEY", c.RateLimit.APIKey},
+	}
+	routes := make(map[string]Limit, len(c.RateLimit.Routes))
+	for route, l := range c.RateLimit.Routes {
+		method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+		path = strings.TrimSpace(path)
+		if !ok || method == "" || !strings.HasPrefix(path, "/") {
+			invalid("rate_limit.routes", "RATE_LIMIT_ROUTES", "%q is not a route like \"POST /login\"", route)
+			continue
+		}
+		route = strings.ToUpper(method) + " " + path
+		routes[route] = l
+		limits = append(limits, namedLimit{"rate_limit.routes", "RATE_LIMIT_ROUTES", l})
+	}
+	c.RateLimit.Routes = routes
+	for _, l := range limits {
+		if l.limit.Requests < 1 || l.limit.Window <= 0 {
+			invalid(l.key, l.env, "at least one request has to be allowed per positive window")
+		}
+	}
+
+	c.trustedProxies = nil
+	for _, proxy := range c.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				invalid("trusted_proxies", "TRUSTED_PROXIES", "%q is neither an address nor a CIDR", proxy)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		c.trustedProxies = append(c.trustedProxies, prefix.Masked())
+	}
+	return errs
+}
+
+// Offset is TimeOffset parsed.
+func (c Config) Offset() helper.Offset {
+	return c.offset
+}
+
+// TrustedProxyPrefixes is TrustedProxies parsed.
+func (c Config) TrustedProxyPrefixes() []netip.Prefix {
+	return c.trustedProxies
+}
+
+// Addr is the address the server listens at.
+func (c Config) Addr() string {
+	return fmt.Sprintf("0.0.0.0:%d", c.Port)
+}
+
+// SecureCookies tells whether cookies should only be sent over HTTPS, which
+// is when BaseURL is an HTTPS URL.
+func (c Config) SecureCookies() bool {
+	return strings.HasPrefix(c.BaseURL, "https://")
+}
+
+// BackupDir is where snapshots are kept: Backup.Dir, or a backups directory
+// next to the database.
+func (c Config) BackupDir() string {
+	if c.Backup.Dir != "" {
+		return c.Backup.Dir
+	}
+	return filepath.Join(filepath.Dir(db.FilePath(c.DataSourceName)), "backups")
+}
+
+// Redacted returns c with its secrets masked, for printing.
+func (c Config) Redacted() Config {
+	mask := func(s *string) {
+		if *s != "" {
+			*s = "********"
+		}
+	}
+	mask(&c.Key)
+	mask(&c.ShareSecret)
+	mask(&c.Backup.Passphrase)
+	if u, err := url.Parse(c.DataSourceName); err == nil && u.User != nil {
+		c.DataSourceName = u.Redacted()
+	}
+	return c
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay is how long to wait for a file being written to settle before
+// reloading it, editors tend to write in several steps.
+const reloadDelay = 200 * time.Millisecond
+
+// Live holds the config of a running server. Only the settings that take
+// effect without a restart are ever replaced, the others keep their value
+// from startup.
+type Live struct {
+	path string
+	cur  atomic.Pointer[Config]
+
+	// mu serializes reloads, so listeners see them in order.
+	mu        sync.Mutex
+	listeners []func(old, next Config)
+}
+
+// NewLive holds cfg, which was loaded from path.
+func NewLive(path string, cfg Config) *Live {
+	l := &Live{path: path}
+	l.cur.Store(&cfg)
+	return l
+}
+
+func (l *Live) Get() Config {
+	return *l.cur.Load()
+}
+
+// OnReload calls f with the old and the next config after every successful
+// reload, even if nothing changed.
+func (l *Live) OnReload(f func(old, next Config)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, f)
+}
+
+// Reload loads the config again and applies the time offset, the allowed SSE
+// origins and the rate limits, but not their algorithm. An invalid config is
+// rejected as a whole. It returns the keys of the changed settings that need a
+// restart to apply.
+//
+// The environment still takes precedence over the file, so only settings not
+// set in the environment can be changed by editing the file.
+func (l *Live) Reload() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	loaded, err := Load(l.path)
+	if err != nil {
+		return nil, err
+	}
+	old := l.Get()
+	cfg := old
+	cfg.TimeOffset, cfg.offset = loaded.TimeOffset, loaded.offset
+	cfg.AllowedSSEOrigins = loaded.AllowedSSEOrigins
+	cfg.RateLimit = loaded.RateLimit
+	cfg.RateLimit.Algorithm = old.RateLimit.Algorithm
+	l.cur.Store(&cfg)
+
+	for _, f := range l.listeners {
+		f(old, cfg)
+	}
+	return needRestart(old, loaded), nil
+}
+
+func needRestart(old, next Config) []string {
+	var keys []string
+	changed := func(key string, differs bool) {
+		if differs {
+			keys = append(keys, key)
+		}
+	}
+	changed("data_source_name", old.DataSourceName != next.DataSourceName)
+	changed("berak_key", old.Key != next.Key)
+	changed("port", old.Port != next.Port)
+	changed("base_url", old.BaseURL != next.BaseURL)
+	changed("visibility", old.Visibility != next.Visibility)
+	changed("share_secret", old.ShareSecret != next.ShareSecret)
+	changed("backup", old.Backup != next.Backup)
+	changed("rate_limit.algorithm", old.RateLimit.Algorithm != next.RateLimit.Algorithm)
+	changed("trusted_proxies", !slices.Equal(old.TrustedProxies, next.TrustedProxies))
+	changed("tracing", old.Tracing != next.Tracing)
+	changed("log", old.Log.Format != next.Log.Format || !maps.Equal(old.Log.Sample, next.Log.Sample))
+	return keys
+}
+
+// Watch reloads the config on SIGHUP and, if it's read from a file, whenever
+// the file is written, until ctx is done.
+func (l *Live) Watch(ctx context.Context, logger *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var fileEvents <-chan fsnotify.Event
+	if l.path != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			logger.Error("failed to create config watcher!", "error", err)
+		} else {
+			defer watcher.Close()
+			// editors often replace the file rather than write to it, which
+			// only its directory sees.
+			err = watcher.Add(filepath.Dir(l.path))
+			if err != nil {
+				logger.Error("failed to watch config file!", "error", err, "path", l.path)
+			} else {
+				fileEvents = watcher.Events
+			}
+		}
+	}
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Info("SIGHUP received, reloading config")
+			l.reload(logger)
+		case event, ok := <-fileEvents:
+			if !ok {
+				fileEvents = nil
+				break
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(l.path) || !event.Has(fsnotify.Write|fsnotify.Create) {
+				break
+			}
+			settle = time.After(reloadDelay)
+		case <-settle:
+			settle = nil
+			logger.Info("config file changed, reloading config", "path", l.path)
+			l.reload(logger)
+		}
+	}
+}
+
+func (l *Live) reload(logger *slog.Logger) {
+	restart, err := l.Reload()
+	if err != nil {
+		logger.Error("invalid config, keeping the current one!", "error", err)
+		return
+	}
+	if len(restart) > 0 {
+		logger.Warn("some changed settings only apply after a restart!", "settings", restart)
+	}
+	logger.Info("config reloaded!")
+}
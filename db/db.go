@@ -1,38 +1,236 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
 
-	_ "github.com/mattn/go-sqlite3"
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
 )
 
-func NewConn(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", dsn)
+// SchemaVersion is stored as the database's user_version. Bump it whenever
+// initSQLite changes the schema.
+const SchemaVersion = 1
+
+// Conn is a database connection that can be swapped for another database
+// file while in use. Queries are written with ? placeholders regardless of
+// the driver.
+type Conn struct {
+	dsn    string
+	driver string
+	db     atomic.Pointer[sql.DB]
+
+	// mu is held for reading by every query, so Swap waits for those running
+	// and the next ones wait for it.
+	mu      sync.RWMutex
+	swapped chan struct{}
+}
+
+// NewConn connects to Postgres when dsn is a postgres:// or postgresql://
+// URL, and to the SQLite file at dsn otherwise.
+func NewConn(dsn string) (*Conn, error) {
+	driver := DriverSQLite
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		driver = DriverPostgres
+	}
+	db, err := open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
-	err = initDB(db)
+	c := &Conn{dsn: dsn, driver: driver, swapped: make(chan struct{})}
+	c.db.Store(db)
+	return c, nil
+}
+
+func open(driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == DriverPostgres {
+		err = initPostgres(db)
+	} else {
+		err = initSQLite(db)
+	}
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 	return db, nil
 }
 
-func initDB(db *sql.DB) error {
-	_, err := db.Exec(`
-	CREATE TABLE IF NOT EXISTS berak (
-	ID INTEGER PRIMARY KEY,
-	timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-	CREATE INDEX IF NOT EXISTS idx_timestamp ON berak(timestamp);
-	CREATE INDEX IF NOT EXISTS idx_ymd ON berak(
-		strftime('%Y', timestamp),
-    	strftime('%m', timestamp),
-    	strftime('%d', timestamp)
-	);
-	`)
-	if err != nil {
-		return err
+func (c *Conn) DSN() string {
+	return c.dsn
+}
+
+func (c *Conn) Driver() string {
+	return c.driver
+}
+
+// Path returns the file the database is stored in, or "" if it isn't a file.
+func (c *Conn) Path() string {
+	if c.driver != DriverSQLite {
+		return ""
+	}
+	return FilePath(c.dsn)
+}
+
+// FilePath returns the file of the SQLite database at dsn, which may be a
+// file: URI with parameters, e.g. "file:berak.db?_fk=1", or "" for an
+// in-memory one.
+func FilePath(dsn string) string {
+	path, isURI := strings.CutPrefix(dsn, "file:")
+	if isURI {
+		path, _, _ = strings.Cut(path, "?")
+		path = strings.TrimPrefix(path, "//")
+	}
+	if path == ":memory:" {
+		return ""
+	}
+	return path
+}
+
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.db.Load().ExecContext(ctx, c.rebind(query), args...)
+}
+
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.db.Load().QueryContext(ctx, c.rebind(query), args...)
+}
+
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.db.Load().QueryRowContext(ctx, c.rebind(query), args...)
+}
+
+func (c *Conn) PingContext(ctx context.Context) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.db.Load().PingContext(ctx)
+}
+
+// tables are the tables every berak database has.
+var tables = []string{"berak", "goals", "achievements", "api_tokens", "sessions", "share_links"}
+
+// CheckSchema checks that the schema is the one this version of berak
+// expects: every table exists and, for SQLite, the schema version matches.
+func (c *Conn) CheckSchema(ctx context.Context) error {
+	if c.driver == DriverSQLite {
+		var version int
+		err := c.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version)
+		if err != nil {
+			return fmt.Errorf("get schema version: %w", err)
+		}
+		if version != SchemaVersion {
+			return fmt.Errorf("schema version is %d, expected %d", version, SchemaVersion)
+		}
+	}
+	for _, table := range tables {
+		_, err := c.ExecContext(ctx, fmt.Sprintf(`SELECT 1 FROM %s LIMIT 0`, table))
+		if err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
 	}
 	return nil
 }
+
+func (c *Conn) Close() error {
+	return c.db.Load().Close()
+}
+
+// rebind turns the ? placeholders of query into the $1, $2, ... that
+// Postgres expects. A ? within a string literal, a quoted identifier or a
+// comment is left as is.
+func (c *Conn) rebind(query string) string {
+	if c.driver != DriverPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var (
+		b strings.Builder
+		n int
+		// end closes the quote or comment being skipped, if any.
+		end string
+	)
+	for i := 0; i < len(query); i++ {
+		rest := query[i:]
+		switch {
+		case end != "":
+			if strings.HasPrefix(rest, end) {
+				b.WriteString(end)
+				i += len(end) - 1
+				end = ""
+				continue
+			}
+		case rest[0] == '\'' || rest[0] == '"':
+			end = rest[:1]
+		case strings.HasPrefix(rest, "--"):
+			end = "\n"
+		case strings.HasPrefix(rest, "/*"):
+			b.WriteString("/*")
+			i++
+			end = "*/"
+			continue
+		case rest[0] == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(query[i])
+	}
+	return b.String()
+}
+
+// Swapped returns a channel that's closed once the database is swapped.
+func (c *Conn) Swapped() <-chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.swapped
+}
+
+// Swap moves the database file at path over the current one and reconnects to
+// it, keeping the rows of the current database in the tables of keep. It waits
+// for the running queries to finish and holds the next ones until it's done,
+// so no write lands in the old file or is missed by keep.
+func (c *Conn) Swap(path string, keep ...string) error {
+	if c.driver != DriverSQLite || c.Path() == "" {
+		return fmt.Errorf("swap %s database: %w", c.driver, errors.ErrUnsupported)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(keep) > 0 {
+		err := copyTables(c.Path(), path, keep)
+		if err != nil {
+			return fmt.Errorf("keep tables: %w", err)
+		}
+	}
+	err := os.Rename(path, c.Path())
+	if err != nil {
+		return fmt.Errorf("move database: %w", err)
+	}
+	// a journal left by the old file would be replayed into the new one.
+	os.Remove(c.Path() + "-journal")
+
+	db, err := open(c.driver, c.dsn)
+	if err != nil {
+		return fmt.Errorf("reconnect: %w", err)
+	}
+	old := c.db.Swap(db)
+	close(c.swapped)
+	c.swapped = make(chan struct{})
+	return old.Close()
+}
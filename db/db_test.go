@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "placeholders",
+			query: `SELECT * FROM berak WHERE id = ? AND timestamp > ?`,
+			want:  `SELECT * FROM berak WHERE id = $1 AND timestamp > $2`,
+		},
+		{
+			name:  "string literal",
+			query: `SELECT '?', ? FROM berak`,
+			want:  `SELECT '?', $1 FROM berak`,
+		},
+		{
+			name:  "escaped quote",
+			query: `SELECT 'it''s ?', ?`,
+			want:  `SELECT 'it''s ?', $1`,
+		},
+		{
+			name:  "quoted identifier",
+			query: `SELECT "a?b" FROM berak WHERE id = ?`,
+			want:  `SELECT "a?b" FROM berak WHERE id = $1`,
+		},
+		{
+			name:  "line comment",
+			query: "SELECT ? -- ?\nFROM berak WHERE id = ?",
+			want:  "SELECT $1 -- ?\nFROM berak WHERE id = $2",
+		},
+		{
+			name:  "block comment",
+			query: `SELECT /* ? */ ? FROM berak`,
+			want:  `SELECT /* ? */ $1 FROM berak`,
+		},
+		{
+			name:  "unterminated comment",
+			query: `SELECT ? /* ?`,
+			want:  `SELECT $1 /* ?`,
+		},
+	}
+	c := &Conn{driver: DriverPostgres}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.rebind(tt.query); got != tt.want {
+				t.Errorf("rebind(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	sqlite := &Conn{driver: DriverSQLite}
+	if got := sqlite.rebind(tests[0].query); got != tests[0].query {
+		t.Errorf("SQLite rebind(%q) = %q, want it unchanged", tests[0].query, got)
+	}
+}
+
+func TestSwapKeepsTables(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	backup, err := NewConn(filepath.Join(dir, "backup.sqlite3"))
+	if err != nil {
+		t.Fatalf("NewConn: %v", err)
+	}
+	_, err = backup.ExecContext(ctx, `INSERT INTO berak DEFAULT VALUES; INSERT INTO api_tokens(name, token_hash, scopes) VALUES('old', 'old', 'admin')`)
+	if err != nil {
+		t.Fatalf("fill backup: %v", err)
+	}
+	backup.Close()
+
+	conn, err := NewConn(filepath.Join(dir, "berak.sqlite3"))
+	if err != nil {
+		t.Fatalf("NewConn: %v", err)
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, `INSERT INTO api_tokens(name, token_hash, scopes) VALUES('new', 'new', 'write')`)
+	if err != nil {
+		t.Fatalf("fill database: %v", err)
+	}
+
+	err = conn.Swap(filepath.Join(dir, "backup.sqlite3"), AuthTables...)
+	if err != nil {
+		t.Fatalf("Swap: %v", err)
+	}
+	var poops int
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM berak`).Scan(&poops)
+	if err != nil || poops != 1 {
+		t.Errorf("💩s after swap = %d, %v, want those of the backup", poops, err)
+	}
+	var names string
+	err = conn.QueryRowContext(ctx, `SELECT GROUP_CONCAT(name) FROM api_tokens`).Scan(&names)
+	if err != nil || names != "new" {
+		t.Errorf("tokens after swap = %q, %v, want those of the swapped out database", names, err)
+	}
+}
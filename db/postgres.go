@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/lib/pq"
+)
+
+// initPostgres creates the same tables as initSQLite. Timestamps of 💩s,
+// goals and achievements are stored as UTC without a time zone, like SQLite's
+// CURRENT_TIMESTAMP.
+func initPostgres(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS berak (
+	id BIGSERIAL PRIMARY KEY,
+	timestamp TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+	);
+	CREATE INDEX IF NOT EXISTS idx_timestamp ON berak(timestamp);
+	CREATE TABLE IF NOT EXISTS goals (
+	id BIGSERIAL PRIMARY KEY,
+	kind TEXT NOT NULL,
+	target INTEGER NOT NULL CHECK (target > 0),
+	created_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+	);
+	CREATE TABLE IF NOT EXISTS achievements (
+	badge_id TEXT PRIMARY KEY,
+	unlocked_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+	);
+	CREATE TABLE IF NOT EXISTS api_tokens (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	expires_at TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS sessions (
+	id_hash TEXT PRIMARY KEY,
+	token_id BIGINT REFERENCES api_tokens(id) ON DELETE CASCADE,
+	scopes TEXT NOT NULL,
+	csrf_token TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS share_links (
+	id BIGSERIAL PRIMARY KEY,
+	from_date DATE NOT NULL,
+	to_date DATE NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	`)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Validate checks that the file at path is an intact berak database that's
+// not newer than this version of berak.
+func Validate(ctx context.Context, path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var integrity string
+	err = db.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&integrity)
+	if err != nil {
+		return fmt.Errorf("not a SQLite database: %w", err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("database is corrupted: %s", integrity)
+	}
+
+	var version int
+	err = db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("get schema version: %w", err)
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("schema version %d is newer than the supported %d", version, SchemaVersion)
+	}
+
+	_, err = db.ExecContext(ctx, `SELECT ID, timestamp FROM berak LIMIT 1`)
+	if err != nil {
+		return fmt.Errorf("not a berak database: %w", err)
+	}
+	return nil
+}
+
+// AuthTables hold who may do what rather than the log itself. A restore keeps
+// those of the current database, so tokens and share links revoked since the
+// backup was taken stay revoked and no one gets logged out.
+var AuthTables = []string{"api_tokens", "sessions", "share_links"}
+
+// copyTables replaces the rows of tables in the SQLite database at to with
+// those of the one at from.
+func copyTables(from, to string, tables []string) error {
+	db, err := sql.Open("sqlite3", to)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	// the tables may be newer than the database at to.
+	err = initSQLite(db)
+	if err != nil {
+		return fmt.Errorf("init schema: %w", err)
+	}
+
+	// attached databases are only known to the connection attaching them.
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, `ATTACH DATABASE ? AS src`, from)
+	if err != nil {
+		return fmt.Errorf("attach database: %w", err)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range tables {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM main.%[1]s; INSERT INTO main.%[1]s SELECT * FROM src.%[1]s`, table))
+		if err != nil {
+			return fmt.Errorf("copy %s: %w", table, err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `DETACH DATABASE src`)
+	return err
+}
+
+func initSQLite(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS berak (
+	ID INTEGER PRIMARY KEY,
+	timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_timestamp ON berak(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_ymd ON berak(
+		strftime('%Y', timestamp),
+    	strftime('%m', timestamp),
+    	strftime('%d', timestamp)
+	);
+	CREATE TABLE IF NOT EXISTS goals (
+	id INTEGER PRIMARY KEY,
+	kind TEXT NOT NULL,
+	target INTEGER NOT NULL CHECK (target > 0),
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS achievements (
+	badge_id TEXT PRIMARY KEY,
+	unlocked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS api_tokens (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL,
+	expires_at DATETIME,
+	last_used_at DATETIME,
+	revoked_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS sessions (
+	id_hash TEXT PRIMARY KEY,
+	token_id INTEGER REFERENCES api_tokens(id) ON DELETE CASCADE,
+	scopes TEXT NOT NULL,
+	csrf_token TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS share_links (
+	id INTEGER PRIMARY KEY,
+	from_date DATE NOT NULL,
+	to_date DATE NOT NULL,
+	expires_at DATETIME NOT NULL,
+	revoked_at DATETIME,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, SchemaVersion))
+	if err != nil {
+		return err
+	}
+	return nil
+}
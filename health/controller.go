@@ -0,0 +1,70 @@
+package health
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/thansetan/berak/helper"
+)
+
+type Authenticator interface {
+	IsAuthenticated(r *http.Request) (bool, error)
+}
+
+type controller struct {
+	logger *slog.Logger
+	svc    *healthService
+	auth   Authenticator
+}
+
+func NewController(svc *healthService, auth Authenticator, logger *slog.Logger) *controller {
+	return &controller{logger, svc, auth}
+}
+
+// Livez tells that the server is up, it doesn't check anything else so a busy
+// or broken dependency doesn't get the server restarted.
+func (c *controller) Livez(w http.ResponseWriter, r *http.Request) {
+	helper.WriteJSON(w, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{StatusOK})
+}
+
+type checkJSON struct {
+	Result
+	Latency   string  `json:"latency"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Readyz runs the readiness checks, answering 503 if any of them failed. Only
+// authenticated callers get the result of every check, as they tell about
+// the database and its file.
+func (c *controller) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := c.svc.Ready(r.Context())
+	checks := make([]checkJSON, len(report.Checks))
+	for i, result := range report.Checks {
+		checks[i] = checkJSON{result, result.Latency.String(), float64(result.Latency) / float64(time.Millisecond)}
+		if result.Status != StatusOK {
+			c.logger.WarnContext(r.Context(), "readiness check failed!", "check", result.Name, "error", result.Error, "took", result.Latency)
+		}
+	}
+
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	authenticated, err := c.auth.IsAuthenticated(r)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to verify token!", "error", err, "remote_addr", r.RemoteAddr)
+	}
+	if !authenticated {
+		helper.WriteJSON(w, code, struct {
+			Status string `json:"status"`
+		}{report.Status})
+		return
+	}
+	helper.WriteJSON(w, code, struct {
+		Status string      `json:"status"`
+		Checks []checkJSON `json:"checks"`
+	}{report.Status, checks})
+}
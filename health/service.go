@@ -0,0 +1,122 @@
+// Package health tells whether the server is alive and whether it's ready to
+// serve requests.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/thansetan/berak/db"
+)
+
+// checkTimeout bounds every check, so a stuck database fails readiness rather
+// than hanging the probe.
+const checkTimeout = 2 * time.Second
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check is a readiness check. Run returns an optional detail about what it
+// found, or why it failed along with the error.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+type Result struct {
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	Detail  string        `json:"detail,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"-"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type healthService struct {
+	checks []Check
+}
+
+func NewService(checks ...Check) *healthService {
+	return &healthService{checks}
+}
+
+// Ready runs every check at once and reports them in the order they were
+// given. The server is ready if all of them pass.
+func (s *healthService) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make([]Result, len(s.checks))}
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, check)
+		}()
+	}
+	wg.Wait()
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	t0 := time.Now()
+	detail, err := check.Run(ctx)
+	result := Result{Name: check.Name, Status: StatusOK, Detail: detail, Latency: time.Since(t0)}
+	if err != nil {
+		result.Status, result.Error = StatusFail, err.Error()
+	}
+	return result
+}
+
+// DatabaseChecks checks that the database answers and has the expected schema
+// and, if it's a file, that the file can be written to.
+func DatabaseChecks(conn *db.Conn) []Check {
+	checks := []Check{
+		{"database", func(ctx context.Context) (string, error) {
+			return conn.Driver(), conn.PingContext(ctx)
+		}},
+		{"migrations", func(ctx context.Context) (string, error) {
+			return "", conn.CheckSchema(ctx)
+		}},
+	}
+	if path := conn.Path(); path != "" {
+		checks = append(checks, Check{"data_file", func(context.Context) (string, error) {
+			return path, writable(path)
+		}})
+	}
+	return checks
+}
+
+// writable checks that both the file and its directory, where SQLite keeps its
+// journal, can be written to.
+func writable(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("close file: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".berak-readyz-*")
+	if err != nil {
+		return fmt.Errorf("create file in directory: %w", err)
+	}
+	return errors.Join(tmp.Close(), os.Remove(tmp.Name()))
+}
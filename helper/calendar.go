@@ -1,5 +1,7 @@
 package helper
 
+import "time"
+
 type MonthData struct {
 	Name string
 	Days int
@@ -33,3 +35,7 @@ func IsLeapYear(year int) bool {
 	}
 	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
 }
+
+func TruncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,20 @@
+package helper
+
+import (
+	"net/http"
+	"time"
+)
+
+// NewCookie returns an HttpOnly cookie for the whole site, which is only sent
+// over HTTPS when secure.
+func NewCookie(name, value string, expires time.Time, secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -51,6 +51,8 @@ func NewOffset(s string) (Offset, error) {
 		offset.f = func(t time.Time) time.Time {
 			return t.AddDate(n, 0, 0)
 		}
+	default:
+		return offset, fmt.Errorf("invalid time unit: %s", separated[1])
 	}
 	offset.s = s
 
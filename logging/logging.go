@@ -0,0 +1,61 @@
+// Package logging builds the logger of the server, whose records carry the ID
+// and trace of the request they were logged for.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/thansetan/berak/tracing"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type requestIDContextKey struct{}
+
+// New returns a logger writing records to w as text or JSON lines. Records
+// logged with a context get the request ID and the trace IDs it carries.
+func New(w io.Writer, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{AddSource: true}
+	var h slog.Handler
+	if format == FormatJSON {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{tracing.NewLogHandler(h)})
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestID returns the ID of the request ctx belongs to, or "" if none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request ID of the context to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
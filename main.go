@@ -7,8 +7,6 @@ import (
 	"fmt"
 	"html/template"
 	"io/fs"
-	"log/slog"
-	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -18,10 +16,21 @@ import (
 
 	"github.com/gorilla/mux"
 	_ "github.com/joho/godotenv/autoload"
+	"github.com/thansetan/berak/backup"
 	"github.com/thansetan/berak/berak"
+	"github.com/thansetan/berak/config"
 	"github.com/thansetan/berak/db"
+	"github.com/thansetan/berak/health"
 	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/logging"
+	"github.com/thansetan/berak/metrics"
 	"github.com/thansetan/berak/middleware"
+	"github.com/thansetan/berak/model"
+	"github.com/thansetan/berak/ratelimit"
+	"github.com/thansetan/berak/session"
+	"github.com/thansetan/berak/share"
+	"github.com/thansetan/berak/token"
+	"github.com/thansetan/berak/tracing"
 )
 
 var (
@@ -33,12 +42,45 @@ var (
 )
 
 func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		AddSource: true,
-	}))
-	db, err := db.NewConn(os.Getenv("DATA_SOURCE_NAME"))
+	logger := logging.New(os.Stdout, logging.FormatText)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "restore":
+			os.Exit(runRestore(logger, os.Args[2:]))
+		case "seed":
+			os.Exit(runSeed(logger, os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+			os.Exit(2)
+		}
+	}
+	cfgPath := os.Getenv(config.PathEnv)
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		logger.Error("invalid configuration!", "error", err)
+		os.Exit(1)
+	}
+	logger = logging.New(os.Stdout, cfg.Log.Format)
+	liveCfg := config.NewLive(cfgPath, cfg)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		logger.Error("failed to set up tracing!", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		// the server context is done by now, so give the last spans some
+		// time of their own.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("failed to flush traces!", "error", err)
+		}
+	}()
+	db, err := db.NewConn(cfg.DataSourceName)
 	if err != nil {
-		logger.Error("failed to establish database connection!", "error", "err")
+		logger.Error("failed to establish database connection!", "error", err)
 		os.Exit(1)
 	}
 
@@ -49,6 +91,12 @@ func main() {
 		"getMonthName": func(monthNumber int) string {
 			return helper.GetMonth(monthNumber).Name
 		},
+		"formatDateTime": func(t time.Time, coarse bool) string {
+			if coarse {
+				return t.Format("02 January 2006")
+			}
+			return t.Format("02 January 2006 at 15:04")
+		},
 		"tai": func(n int) string {
 			if n == 0 {
 				return "-"
@@ -67,39 +115,58 @@ func main() {
 		logger.Error("failed to load template!", "error", err)
 		os.Exit(1)
 	}
-	offset, err := helper.NewOffset(os.Getenv("TIME_OFFSET"))
+	reg := metrics.NewRegistry()
+	repo := berak.NewInstrumentedRepo(berak.NewRepo(db), reg)
+	svc := berak.NewService(repo, db, cfg.Offset())
+	reg.Collect(svc.CollectMetrics)
+	tokenSvc := token.NewService(token.NewRepo(db), cfg.Key)
+	tokenController := token.NewController(tokenSvc, logger)
+	auth := middleware.NewAuth(tokenSvc, logger)
+	controller := berak.NewController(svc, tmpl, liveCfg, auth, reg, logger)
+	sessionSvc := session.NewService(session.NewRepo(db), tokenSvc)
+	sessionController := session.NewController(sessionSvc, tmpl, cfg, logger)
+	shareSvc, err := share.NewService(share.NewRepo(db), cfg.ShareSecret)
 	if err != nil {
-		logger.Error("failed to parse offset!", "error", err)
+		logger.Error("failed to create share service!", "error", err)
 		os.Exit(1)
 	}
-	repo := berak.NewRepo(db)
-	svc := berak.NewService(repo, offset)
-	controller := berak.NewController(svc, tmpl, logger)
+	if cfg.ShareSecret == "" {
+		logger.Warn("SHARE_SECRET is not set, share links won't survive a restart")
+	}
+	shareController := share.NewController(shareSvc, tmpl, cfg, logger)
+	backupSvc := backup.NewService(db, cfg.BackupDir(), cfg.Backup.Keep, cfg.Backup.Passphrase, logger)
+	backupController := backup.NewController(backupSvc, logger)
 
+	metricsMW := middleware.NewMetrics(reg)
+	tracingMW := middleware.NewTracing()
 	r := mux.NewRouter()
+	r.Use(metricsMW.Route, tracingMW.Route)
 	r.NotFoundHandler = http.HandlerFunc(controller.FourOFour)
 	r.MethodNotAllowedHandler = http.HandlerFunc(controller.FourOFour)
 
-	apiKeyRateLimiter := middleware.NewRateLimit(1, time.Minute, 1*time.Hour, func(r *http.Request) string {
-		return r.Header.Get("X-Api-Key")
-	})
+	limiter, err := ratelimit.New(cfg.RateLimit.Algorithm)
+	if err != nil {
+		logger.Error("failed to create rate limiter!", "error", err)
+		os.Exit(1)
+	}
+	apiKeyRateLimiter := middleware.NewRateLimit(limiter, cfg.RateLimit.APIKey.Limit(), func(r *http.Request) string {
+		return "api_key:" + r.Header.Get("X-Api-Key")
+	}, logger)
+	ipRateLimiter := middleware.NewRateLimit(limiter, cfg.RateLimit.IP.Limit(), func(r *http.Request) string {
+		return "ip:" + middleware.ClientIP(r)
+	}, logger)
+	ipRateLimiter.SetLimits(cfg.RateLimit.IP.Limit(), cfg.RateLimit.RouteLimits())
 
-	ipRateLimiter := middleware.NewRateLimit(5, time.Minute, 1*time.Hour, func(r *http.Request) string {
-		if xff := strings.TrimSpace(r.Header.Get("X-Forwarded-For")); xff != "" {
-			parts := strings.Split(xff, ",")
-			return strings.TrimSpace(parts[0])
-		}
-		if xr := strings.TrimSpace(r.Header.Get("X-Real-Ip")); xr != "" {
-			return xr
-		}
-		host, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			return r.RemoteAddr
-		}
-		return host
-	})
+	rateLimitRejections := reg.Counter("berak_rate_limit_rejections_total", "Number of requests rejected by a rate limiter.", "limiter")
+	apiKeyRateLimiter.OnReject(func(*http.Request) { rateLimitRejections.Inc("api_key") })
+	ipRateLimiter.OnReject(func(*http.Request) { rateLimitRejections.Inc("ip") })
+
+	loggerMW := middleware.NewLogger(logger, cfg.Log.Sample)
+	sessionMW := middleware.NewSession(sessionSvc, logger)
+	privacyMW := middleware.NewPrivacy(cfg.Visibility, auth, shareSvc)
 
-	loggerMW := middleware.NewLogger(logger)
+	healthSvc := health.NewService(append(health.DatabaseChecks(db), health.Check{Name: "events", Run: controller.CheckEvents})...)
+	healthController := health.NewController(healthSvc, auth, logger)
 
 	{
 		r.Path("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -107,15 +174,44 @@ func main() {
 			http.Redirect(w, r, fmt.Sprintf("/%d", now.Year()), http.StatusTemporaryRedirect)
 		})
 		r.Path("/sse").HandlerFunc(controller.Event).Methods(http.MethodGet)
-		r.Path("/berak").HandlerFunc(ipRateLimiter.Handle(protected(apiKeyRateLimiter.Handle(http.HandlerFunc(controller.Create))))).Methods(http.MethodPost)
-		r.Path("/berak").HandlerFunc(ipRateLimiter.Handle(protected(apiKeyRateLimiter.Handle(http.HandlerFunc(controller.Delete))))).Methods(http.MethodDelete)
+		r.Path("/berak").HandlerFunc(ipRateLimiter.Handle(auth.Require(model.ScopeWrite, apiKeyRateLimiter.Handle(http.HandlerFunc(controller.Create))))).Methods(http.MethodPost)
+		r.Path("/berak").HandlerFunc(ipRateLimiter.Handle(auth.Require(model.ScopeDelete, apiKeyRateLimiter.Handle(http.HandlerFunc(controller.Delete))))).Methods(http.MethodDelete)
 		r.Path("/{year:[0-9]+}").HandlerFunc(controller.GetMonthly).Methods(http.MethodGet)
 		r.Path("/{year:[0-9]+}/{month:[0-9]+}").HandlerFunc(controller.GetDaily).Methods(http.MethodGet)
 		r.Path("/last_poop").HandlerFunc(controller.GetLastPoopTime).Methods(http.MethodGet)
-		r.Path("/healthcheck").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			_, _ = w.Write([]byte("ok"))
-		})
-		r.Path("/download").HandlerFunc(protected(http.HandlerFunc(controller.GetSQLiteFile))).Methods(http.MethodGet)
+		r.Path("/all").HandlerFunc(controller.GetAll).Methods(http.MethodGet)
+		r.Path("/records").HandlerFunc(controller.GetRecords).Methods(http.MethodGet)
+		r.Path("/badges").HandlerFunc(controller.GetBadges).Methods(http.MethodGet)
+		r.Path("/api/v1/records").HandlerFunc(controller.GetRecordsJSON).Methods(http.MethodGet)
+		r.Path("/api/v1/stats").HandlerFunc(controller.GetStatisticsJSON).Methods(http.MethodGet)
+		r.Path("/api/v1/months/{year:[0-9]+}/{month:[0-9]+}").HandlerFunc(controller.GetDailyJSON).Methods(http.MethodGet)
+		r.Path("/api/v1/anomalies").HandlerFunc(controller.GetAnomalies).Methods(http.MethodGet)
+		r.Path("/api/v1/forecast").HandlerFunc(controller.GetForecast).Methods(http.MethodGet)
+		r.Path("/login").HandlerFunc(sessionController.LoginPage).Methods(http.MethodGet)
+		r.Path("/login").HandlerFunc(ipRateLimiter.Handle(http.HandlerFunc(sessionController.Login))).Methods(http.MethodPost)
+		r.Path("/logout").HandlerFunc(sessionController.Logout).Methods(http.MethodPost)
+		// the domain gauges tell the time of the last 💩 to the second, whatever
+		// the visibility. Scrapers send a metrics token in X-Api-Key.
+		r.Path("/metrics").HandlerFunc(auth.Require(model.ScopeMetrics, reg.Handler(logger))).Methods(http.MethodGet)
+		r.Path("/livez").HandlerFunc(healthController.Livez).Methods(http.MethodGet, http.MethodHead)
+		r.Path("/healthcheck").HandlerFunc(healthController.Livez).Methods(http.MethodGet, http.MethodHead)
+		r.Path("/readyz").HandlerFunc(healthController.Readyz).Methods(http.MethodGet, http.MethodHead)
+		r.Path("/restore").HandlerFunc(auth.Require(model.ScopeAdmin, http.HandlerFunc(backupController.Restore))).Methods(http.MethodPost)
+		r.Path("/download").HandlerFunc(auth.Require(model.ScopeExport, http.HandlerFunc(backupController.Download))).Methods(http.MethodGet)
+		r.Path(middleware.ExportPath).HandlerFunc(auth.Require(model.ScopeExport, http.HandlerFunc(controller.Export))).Methods(http.MethodGet)
+		r.Path("/share/{token}").HandlerFunc(shareController.Open).Methods(http.MethodGet)
+		r.Path("/shares").HandlerFunc(auth.Require(model.ScopeExport, http.HandlerFunc(shareController.Page))).Methods(http.MethodGet)
+		r.Path("/api/v1/shares").HandlerFunc(auth.Require(model.ScopeExport, http.HandlerFunc(shareController.GetAll))).Methods(http.MethodGet)
+		r.Path("/api/v1/shares").HandlerFunc(auth.Require(model.ScopeExport, http.HandlerFunc(shareController.Create))).Methods(http.MethodPost)
+		r.Path("/api/v1/shares/{id:[0-9]+}").HandlerFunc(auth.Require(model.ScopeExport, http.HandlerFunc(shareController.Revoke))).Methods(http.MethodDelete)
+		r.Path("/api/v1/goals").HandlerFunc(auth.Require(model.ScopeExport, http.HandlerFunc(controller.GetGoals))).Methods(http.MethodGet)
+		r.Path("/api/v1/goals").HandlerFunc(auth.Require(model.ScopeWrite, http.HandlerFunc(controller.CreateGoal))).Methods(http.MethodPost)
+		r.Path("/api/v1/goals/{id:[0-9]+}").HandlerFunc(auth.Require(model.ScopeExport, http.HandlerFunc(controller.GetGoal))).Methods(http.MethodGet)
+		r.Path("/api/v1/goals/{id:[0-9]+}").HandlerFunc(auth.Require(model.ScopeWrite, http.HandlerFunc(controller.UpdateGoal))).Methods(http.MethodPut)
+		r.Path("/api/v1/goals/{id:[0-9]+}").HandlerFunc(auth.Require(model.ScopeDelete, http.HandlerFunc(controller.DeleteGoal))).Methods(http.MethodDelete)
+		r.Path("/api/v1/tokens").HandlerFunc(auth.Require(model.ScopeAdmin, http.HandlerFunc(tokenController.GetAll))).Methods(http.MethodGet)
+		r.Path("/api/v1/tokens").HandlerFunc(auth.Require(model.ScopeAdmin, http.HandlerFunc(tokenController.Create))).Methods(http.MethodPost)
+		r.Path("/api/v1/tokens/{id:[0-9]+}").HandlerFunc(auth.Require(model.ScopeAdmin, http.HandlerFunc(tokenController.Revoke))).Methods(http.MethodDelete)
 	}
 
 	staticFilesFS, err := fs.Sub(staticDirFS, "static")
@@ -137,11 +233,28 @@ func main() {
 	})))
 
 	srv := new(http.Server)
-	srv.Handler = loggerMW.Handle(r)
-	srv.Addr = net.JoinHostPort("0.0.0.0", os.Getenv("PORT"))
+	requestIDMW := middleware.NewRequestID()
+	realIPMW := middleware.NewRealIP(cfg.TrustedProxyPrefixes())
+	srv.Handler = requestIDMW.Handle(realIPMW.Handle(tracingMW.Handle(metricsMW.Handle(loggerMW.Handle(sessionMW.Handle(privacyMW.Handle(r)))))))
+	srv.Addr = cfg.Addr()
+
+	liveCfg.OnReload(func(_, next config.Config) {
+		svc.SetOffset(next.Offset())
+		apiKeyRateLimiter.SetLimits(next.RateLimit.APIKey.Limit(), nil)
+		ipRateLimiter.SetLimits(next.RateLimit.IP.Limit(), next.RateLimit.RouteLimits())
+	})
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
 	defer cancel()
+	go liveCfg.Watch(ctx, logger)
+	go limiter.Run(ctx, 1*time.Hour)
+	if cfg.Backup.Dir != "" {
+		if db.Path() != "" {
+			go backupSvc.Run(ctx, time.Duration(cfg.Backup.Interval))
+		} else {
+			logger.Warn("scheduled backups are only taken of SQLite databases!", "driver", db.Driver())
+		}
+	}
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("failed to listen and serve HTTP connection!", "error", err)
@@ -155,13 +268,3 @@ func main() {
 		logger.Error("failed to shut down server!", "error", err)
 	}
 }
-
-func protected(next http.Handler) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("X-Api-Key") != os.Getenv("BERAK_KEY") {
-			helper.WriteMessage(w, http.StatusUnauthorized, "gaboleh 😡")
-			return
-		}
-		next.ServeHTTP(w, r)
-	}
-}
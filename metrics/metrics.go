@@ -0,0 +1,257 @@
+// Package metrics keeps counters, gauges and histograms and serves them in the
+// Prometheus text format, covering just what berak needs of a client library.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefBuckets are the upper bounds of the histogram buckets, in seconds, used
+// for request and query durations.
+var DefBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Sample is the value of a gauge read by a collector.
+type Sample struct {
+	Name, Help string
+	Value      float64
+}
+
+// CollectFunc reads gauges on every scrape, for values that live elsewhere.
+type CollectFunc func(ctx context.Context) ([]Sample, error)
+
+type Registry struct {
+	mu         sync.Mutex
+	names      map[string]bool
+	families   []*family
+	collectors []CollectFunc
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[f.name] {
+		panic(fmt.Sprintf("metrics: %s registered twice", f.name))
+	}
+	r.names[f.name] = true
+	r.families = append(r.families, f)
+	return f
+}
+
+// Counter registers a counter partitioned by labels.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(newFamily(name, help, "counter", labels, nil))}
+}
+
+// Gauge registers a gauge partitioned by labels.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(newFamily(name, help, "gauge", labels, nil))}
+}
+
+// Histogram registers a histogram partitioned by labels, with buckets as the
+// upper bounds of its buckets.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{r.register(newFamily(name, help, "histogram", labels, buckets))}
+}
+
+// Collect registers f to be called on every scrape.
+func (r *Registry) Collect(f CollectFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, f)
+}
+
+// Handler serves the metrics. A failing collector is logged and left out, the
+// other metrics are still served.
+func (r *Registry) Handler(logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		families := slices.Clone(r.families)
+		collectors := slices.Clone(r.collectors)
+		r.mu.Unlock()
+
+		var samples []Sample
+		for _, collect := range collectors {
+			s, err := collect(req.Context())
+			if err != nil {
+				logger.ErrorContext(req.Context(), "failed to collect metrics!", "error", err)
+				continue
+			}
+			samples = append(samples, s...)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		for _, f := range families {
+			f.write(bw)
+		}
+		for _, s := range samples {
+			fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", s.Name, escapeHelp(s.Help), s.Name, s.Name, formatFloat(s.Value))
+		}
+		bw.Flush()
+	})
+}
+
+type Counter struct{ f *family }
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which mustn't be negative.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+type Gauge struct{ f *family }
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.update(labelValues, func(s *series) { s.value = v })
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Value returns the current value, 0 if it was never set.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	var v float64
+	g.f.update(labelValues, func(s *series) { v = s.value })
+	return v
+}
+
+type Histogram struct{ f *family }
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.update(labelValues, func(s *series) {
+		s.value += v
+		s.count++
+		for i, bound := range h.f.buckets {
+			if v <= bound {
+				s.buckets[i]++
+			}
+		}
+	})
+}
+
+// ObserveSince observes the seconds passed since t0.
+func (h *Histogram) ObserveSince(t0 time.Time, labelValues ...string) {
+	h.Observe(time.Since(t0).Seconds(), labelValues...)
+}
+
+type family struct {
+	name, help, typ string
+	labels          []string
+	buckets         []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series holds the value of a family for one set of label values. For a
+// histogram, value is the sum and buckets are cumulative.
+type series struct {
+	labelValues []string
+	value       float64
+	count       uint64
+	buckets     []uint64
+}
+
+func newFamily(name, help, typ string, labels []string, buckets []float64) *family {
+	return &family{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: make(map[string]*series)}
+}
+
+func (f *family) update(labelValues []string, update func(*series)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues), buckets: make([]uint64, len(f.buckets))}
+		f.series[key] = s
+	}
+	update(s)
+}
+
+func (f *family) write(w io.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.typ)
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		s := f.series[k]
+		if f.typ != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues, ""), formatFloat(s.value))
+			continue
+		}
+		for i, bound := range f.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, formatFloat(bound)), s.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.labelValues, ""), formatFloat(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.labelValues, ""), s.count)
+	}
+}
+
+// formatLabels formats the labels along with le, the bucket bound, if given.
+func formatLabels(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -3,24 +3,35 @@ package middleware
 import (
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"strings"
 	"time"
 )
 
 type Logger struct {
 	logger *slog.Logger
+	// sample maps paths, or path prefixes ending with a slash, to the share
+	// of their successful requests that get logged.
+	sample map[string]float64
 }
 
-func NewLogger(logger *slog.Logger) *Logger {
+// NewLogger returns a logger of every request, but only of a sample of the
+// successful requests to the paths in sample. Failed requests are always
+// logged.
+func NewLogger(logger *slog.Logger, sample map[string]float64) *Logger {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	return &Logger{logger}
+	return &Logger{logger, sample}
 }
 
+// wrappedResponseWriter keeps the status code, which is 200 unless another one
+// is written, and the size of the body.
 type wrappedResponseWriter struct {
 	http.ResponseWriter
-	code int
+	code  int
+	bytes int
 }
 
 func (wrw *wrappedResponseWriter) Flush() {
@@ -34,23 +45,60 @@ func (wrw *wrappedResponseWriter) WriteHeader(code int) {
 	wrw.ResponseWriter.WriteHeader(code)
 }
 
+func (wrw *wrappedResponseWriter) Write(b []byte) (int, error) {
+	n, err := wrw.ResponseWriter.Write(b)
+	wrw.bytes += n
+	return n, err
+}
+
+func (wrw *wrappedResponseWriter) Unwrap() http.ResponseWriter {
+	return wrw.ResponseWriter
+}
+
 func (l *Logger) Handle(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path == "/sse" {
-			next.ServeHTTP(w, r)
-			return
-		}
 		wrw := &wrappedResponseWriter{
 			ResponseWriter: w,
+			code:           http.StatusOK,
 		}
 		t0 := time.Now()
 		next.ServeHTTP(wrw, r)
-		if wrw.code < 400 {
-			l.logger.InfoContext(r.Context(), fmt.Sprintf("%s %s %s", r.Method, r.RequestURI, r.Proto), "remote_addr", r.RemoteAddr, "code", wrw.code, "took", time.Since(t0))
-		} else if wrw.code < 500 {
-			l.logger.WarnContext(r.Context(), fmt.Sprintf("%s %s %s", r.Method, r.RequestURI, r.Proto), "remote_addr", r.RemoteAddr, "code", wrw.code, "took", time.Since(t0))
-		} else {
-			l.logger.ErrorContext(r.Context(), fmt.Sprintf("%s %s %s", r.Method, r.RequestURI, r.Proto), "remote_addr", r.RemoteAddr, "code", wrw.code, "took", time.Since(t0))
+		if wrw.code < 400 && !l.sampled(r.URL.Path) {
+			return
+		}
+
+		level := slog.LevelInfo
+		if wrw.code >= 500 {
+			level = slog.LevelError
+		} else if wrw.code >= 400 {
+			level = slog.LevelWarn
+		}
+		uri := r.RequestURI
+		if path := redactPath(r.URL.Path); path != r.URL.Path {
+			uri = path
+		}
+		l.logger.Log(r.Context(), level, fmt.Sprintf("%s %s %s", r.Method, uri, r.Proto),
+			"remote_addr", r.RemoteAddr,
+			"client_ip", ClientIP(r),
+			"user_agent", r.UserAgent(),
+			"code", wrw.code,
+			"bytes", wrw.bytes,
+			"took", time.Since(t0),
+		)
+	}
+}
+
+// sampled tells whether a successful request to path gets logged, going by the
+// rate of the path itself or else its longest configured prefix.
+func (l *Logger) sampled(path string) bool {
+	rate, ok := l.sample[path]
+	if !ok {
+		longest := -1
+		for prefix, r := range l.sample {
+			if strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix) && len(prefix) > longest {
+				rate, ok, longest = r, true, len(prefix)
+			}
 		}
 	}
+	return !ok || rand.Float64() < rate
 }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/thansetan/berak/metrics"
+)
+
+type routeContextKey struct{}
+
+// unmatchedRoute labels the requests no route matched, so the label values
+// can't be made up by clients.
+const unmatchedRoute = "unmatched"
+
+type Metrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+}
+
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		requests: reg.Counter("http_requests_total", "Number of HTTP requests by route and status.", "method", "route", "code"),
+		duration: reg.Histogram("http_request_duration_seconds", "Duration of HTTP requests by route.", metrics.DefBuckets, "method", "route"),
+	}
+}
+
+// metricsResponseWriter keeps the status code, which is 200 unless another one
+// is written.
+type metricsResponseWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (mrw *metricsResponseWriter) WriteHeader(code int) {
+	mrw.code = code
+	mrw.ResponseWriter.WriteHeader(code)
+}
+
+func (mrw *metricsResponseWriter) Unwrap() http.ResponseWriter {
+	return mrw.ResponseWriter
+}
+
+// Handle counts and times every request. It has to wrap the router, which
+// records the matched route with Route.
+func (m *Metrics) Handle(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		route := unmatchedRoute
+		r = r.WithContext(context.WithValue(r.Context(), routeContextKey{}, &route))
+		mrw := &metricsResponseWriter{ResponseWriter: w, code: http.StatusOK}
+		t0 := time.Now()
+		next.ServeHTTP(mrw, r)
+
+		method := normalizeMethod(r.Method)
+		m.duration.ObserveSince(t0, method, route)
+		m.requests.Inc(method, route, strconv.Itoa(mrw.code))
+	}
+}
+
+// Route is a router middleware recording the path template of the matched
+// route for Handle.
+func (m *Metrics) Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := r.Context().Value(routeContextKey{}).(*string)
+		if current := mux.CurrentRoute(r); ok && current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				*route = tmpl
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "other"
+}
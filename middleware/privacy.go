@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/model"
+)
+
+type visibilityContextKey struct{}
+
+// publicPathPrefixes are reachable without logging in even when the log is
+// private, so one can still log in.
+var publicPathPrefixes = []string{"/login", "/logout", sharePathPrefix, "/healthcheck", "/livez", "/readyz", "/css/", "/js/", "/img/"}
+
+type Privacy struct {
+	visibility model.Visibility
+	auth       *Auth
+	shares     ShareVerifier
+}
+
+func NewPrivacy(visibility model.Visibility, auth *Auth, shares ShareVerifier) *Privacy {
+	return &Privacy{visibility, auth, shares}
+}
+
+// Handle stores the visibility that applies to the request in its context:
+// the owner always sees everything, anyone else gets the configured one.
+// A share link lifts that for what it shares, and also grants its export.
+// When the log is private, anonymous viewers are sent to the login page.
+func (p *Privacy) Handle(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		visibility := p.visibility
+		authenticated := false
+		if visibility != model.VisibilityPublic || r.URL.Path == ExportPath {
+			var err error
+			authenticated, err = p.auth.IsAuthenticated(r)
+			if err != nil {
+				p.auth.logger.ErrorContext(r.Context(), "failed to verify token!", "error", err, "remote_addr", r.RemoteAddr)
+				helper.OurFault(w)
+				return
+			}
+			if authenticated {
+				visibility = model.VisibilityPublic
+			}
+		}
+
+		if !authenticated && (visibility != model.VisibilityPublic || r.URL.Path == ExportPath) {
+			l, ok, err := p.sharedLink(r)
+			if err != nil {
+				p.auth.logger.ErrorContext(r.Context(), "failed to verify share link!", "error", err, "remote_addr", r.RemoteAddr)
+				helper.OurFault(w)
+				return
+			}
+			if ok {
+				visibility = model.VisibilityPublic
+				r = r.WithContext(context.WithValue(r.Context(), shareContextKey{}, l))
+			}
+		}
+
+		if visibility == model.VisibilityPrivate && !isPublicPath(r.URL.Path) {
+			if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			helper.WriteMessage(w, http.StatusUnauthorized, "gaboleh 😡")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), visibilityContextKey{}, visibility)))
+	}
+}
+
+// sharedLink returns the link of the request's share cookie if it covers the
+// request.
+func (p *Privacy) sharedLink(r *http.Request) (model.ShareLink, bool, error) {
+	cookie, err := r.Cookie(ShareCookieName)
+	if err != nil {
+		return model.ShareLink{}, false, nil
+	}
+	l, ok, err := p.shares.Verify(r.Context(), cookie.Value)
+	if err != nil || !ok || !shareCovers(l, r) {
+		return model.ShareLink{}, false, err
+	}
+	return l, true, nil
+}
+
+func VisibilityFromContext(ctx context.Context) model.Visibility {
+	visibility, ok := ctx.Value(visibilityContextKey{}).(model.Visibility)
+	if !ok {
+		return model.VisibilityPublic
+	}
+	return visibility
+}
+
+func isPublicPath(path string) bool {
+	for _, prefix := range publicPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,77 +1,122 @@
 package middleware
 
 import (
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
-	"sync"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/ratelimit"
 )
 
 type RateLimit struct {
-	store                     *sync.Map
-	keyGetter                 func(r *http.Request) string
-	maxVisitCount             uint64
-	duration, cleanupDuration time.Duration
+	limiter   ratelimit.Limiter
+	keyGetter func(r *http.Request) string
+	limits    atomic.Pointer[limits]
+	onReject  func(r *http.Request)
+	logger    *slog.Logger
 }
 
-type visitor struct {
-	mu          *sync.RWMutex
-	windowStart time.Time
-	count       uint64
+type limits struct {
+	def ratelimit.Limit
+	// routes maps routes, named like "POST /login", to the limits replacing
+	// def for them.
+	routes map[string]ratelimit.Limit
 }
 
-func NewRateLimit(maxVisitCount uint64, duration, cleanupDuration time.Duration, keyGetter func(*http.Request) string) *RateLimit {
-	rl := new(RateLimit)
-	rl.maxVisitCount = (maxVisitCount)
-	rl.duration = duration
-	rl.cleanupDuration = cleanupDuration
-	rl.keyGetter = keyGetter
-	rl.store = new(sync.Map)
-	go rl.cleanup()
-
+// NewRateLimit returns a middleware allowing limit requests per key, as told
+// by keyGetter, counted by limiter.
+func NewRateLimit(limiter ratelimit.Limiter, limit ratelimit.Limit, keyGetter func(*http.Request) string, logger *slog.Logger) *RateLimit {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	rl := &RateLimit{limiter: limiter, keyGetter: keyGetter, logger: logger}
+	rl.SetLimits(limit, nil)
 	return rl
 }
 
+// SetLimits replaces the limits, which apply to the requests counted so far
+// too. The routes with a limit of their own are counted apart from the others.
+func (rl *RateLimit) SetLimits(def ratelimit.Limit, routes map[string]ratelimit.Limit) {
+	rl.limits.Store(&limits{def, routes})
+}
+
+// OnReject has f called for every request rejected for exceeding the limit.
+// It's meant to be called before the limiter handles any request.
+func (rl *RateLimit) OnReject(f func(r *http.Request)) {
+	rl.onReject = f
+}
+
+// Handle lets the request through if its key is within the limit, and tells
+// the client about the limit in the RateLimit-* headers, or Retry-After once
+// it's exceeded.
 func (rl *RateLimit) Handle(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		key := rl.keyGetter(r)
-		valAny, _ := rl.store.LoadOrStore(key, &visitor{
-			windowStart: time.Now(),
-			mu:          new(sync.RWMutex),
-		})
-		val := valAny.(*visitor)
-
-		val.mu.Lock()
-		if time.Since(val.windowStart) > rl.duration {
-			val.count = 0
-			val.windowStart = time.Now()
+		limits := rl.limits.Load()
+		key, limit := rl.keyGetter(r), limits.def
+		if route := routeName(r); route != "" {
+			if l, ok := limits.routes[route]; ok {
+				key, limit = route+" "+key, l
+			}
 		}
 
-		if time.Since(val.windowStart) <= rl.duration && val.count == rl.maxVisitCount {
-			val.mu.Unlock()
+		result, err := rl.limiter.Allow(r.Context(), key, limit)
+		if err != nil {
+			// better let a few requests too many through than refuse them
+			// all while the limiter is down.
+			rl.logger.ErrorContext(r.Context(), "failed to check rate limit!", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		setRateLimitHeaders(w.Header(), limit, result)
+		if !result.Allowed {
+			if rl.onReject != nil {
+				rl.onReject(r)
+			}
+			w.Header().Set("Retry-After", strconv.FormatInt(seconds(result.RetryAfter), 10))
 			helper.WriteMessage(w, http.StatusTooManyRequests, "kecepeten 😡!")
 			return
 		}
-		val.count++
-		val.mu.Unlock()
 
 		next.ServeHTTP(w, r)
 	}
 }
 
-func (rl *RateLimit) cleanup() {
-	for range time.Tick(rl.cleanupDuration) {
-		rl.store.Range(func(key, value any) bool {
-			v := value.(*visitor)
-			v.mu.RLock()
-			windowPassed := time.Since(v.windowStart) > rl.duration
-			v.mu.RUnlock()
-			if windowPassed {
-				rl.store.Delete(key)
-			}
+// routeName names the matched route after its method and path template, e.g.
+// "POST /login", or returns "" if it can't.
+func routeName(r *http.Request) string {
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return ""
+	}
+	tmpl, err := current.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return r.Method + " " + tmpl
+}
 
-			return true
-		})
+// setRateLimitHeaders sets the headers of the IETF RateLimit draft. When
+// several limiters handle a request, the one with the fewest requests left
+// is told.
+func setRateLimitHeaders(h http.Header, limit ratelimit.Limit, result ratelimit.Result) {
+	if v := h.Get("RateLimit-Remaining"); v != "" {
+		if remaining, err := strconv.ParseUint(v, 10, 64); err == nil && remaining < result.Remaining {
+			return
+		}
 	}
+	h.Set("RateLimit-Limit", strconv.FormatUint(result.Limit, 10))
+	h.Set("RateLimit-Remaining", strconv.FormatUint(result.Remaining, 10))
+	h.Set("RateLimit-Reset", strconv.FormatInt(seconds(result.Reset), 10))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, seconds(limit.Window)))
+}
+
+// seconds rounds d up to whole seconds, so clients don't retry too early.
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
 }
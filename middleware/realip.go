@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPContextKey struct{}
+
+type RealIP struct {
+	trustedProxies []netip.Prefix
+}
+
+// NewRealIP returns a middleware finding out the IP of the client, believing
+// the X-Forwarded-For and X-Real-Ip headers only when they're set by one of
+// trustedProxies. Without any, the client is whoever connected.
+func NewRealIP(trustedProxies []netip.Prefix) *RealIP {
+	return &RealIP{trustedProxies}
+}
+
+// Handle stores the IP of the client in the context of the request, for
+// ClientIP.
+func (m *RealIP) Handle(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := m.clientIP(r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPContextKey{}, ip)))
+	}
+}
+
+// clientIP walks the proxies the request went through from the closest one,
+// and returns the first address that isn't a trusted proxy.
+func (m *RealIP) clientIP(r *http.Request) string {
+	remote := remoteIP(r)
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !m.trusted(addr) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		if xr := strings.TrimSpace(r.Header.Get("X-Real-Ip")); xr != "" {
+			hops = []string{xr}
+		}
+	}
+	client := addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// whatever is left was written by someone we don't trust.
+			break
+		}
+		client = hop.Unmap()
+		if !m.trusted(client) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func (m *RealIP) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range m.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP of the client, as found by RealIP, or else the
+// address it connected from.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
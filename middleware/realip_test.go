@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("127.0.0.1/32"),
+		netip.MustParsePrefix("::1/128"),
+	}
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		xff        []string
+		xRealIP    string
+		want       string
+	}{
+		{
+			name:       "no trusted proxies",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"1.2.3.4"},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "untrusted remote",
+			trusted:    trusted,
+			remoteAddr: "5.6.7.8:1234",
+			xff:        []string{"1.2.3.4"},
+			xRealIP:    "1.2.3.4",
+			want:       "5.6.7.8",
+		},
+		{
+			name:       "trusted remote without headers",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "trusted remote",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"1.2.3.4"},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "spoofed hops",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"9.9.9.9, 10.0.0.3, 1.2.3.4"},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "chain of trusted proxies",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"9.9.9.9, 1.2.3.4, 10.0.0.2"},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "all hops trusted",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"10.0.0.3, 10.0.0.2"},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "garbage hop",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"1.2.3.4, garbage, 10.0.0.2"},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "several headers",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:1234",
+			xff:        []string{"9.9.9.9, 1.2.3.4", "10.0.0.2"},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "X-Real-Ip",
+			trusted:    trusted,
+			remoteAddr: "127.0.0.1:1234",
+			xRealIP:    " 1.2.3.4 ",
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "X-Forwarded-For over X-Real-Ip",
+			trusted:    trusted,
+			remoteAddr: "127.0.0.1:1234",
+			xff:        []string{"1.2.3.4"},
+			xRealIP:    "9.9.9.9",
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "IPv6",
+			trusted:    trusted,
+			remoteAddr: "[::1]:1234",
+			xff:        []string{"2001:db8::1"},
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "IPv4-mapped IPv6",
+			trusted:    trusted,
+			remoteAddr: "[::ffff:10.0.0.1]:1234",
+			xff:        []string{"::ffff:1.2.3.4, ::ffff:10.0.0.2"},
+			want:       "1.2.3.4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.xRealIP != "" {
+				r.Header.Set("X-Real-Ip", tt.xRealIP)
+			}
+
+			var got string
+			NewRealIP(tt.trusted).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = ClientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutRealIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if got := ClientIP(r); got != "10.0.0.1" {
+		t.Errorf("ClientIP = %q, want %q", got, "10.0.0.1")
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/logging"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id"
+	// maxRequestIDLength keeps a client from filling the logs through its
+	// request ID.
+	maxRequestIDLength = 128
+)
+
+type RequestID struct{}
+
+func NewRequestID() *RequestID {
+	return &RequestID{}
+}
+
+// Handle gives every request an ID, stored in its context and sent back in
+// the X-Request-Id header. A valid ID sent by the client, e.g. by a proxy in
+// front, is kept so the logs of both can be matched.
+func (m *RequestID) Handle(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(id) {
+			var err error
+			id, err = helper.RandomString(12)
+			if err != nil {
+				helper.OurFault(w)
+				return
+			}
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	}
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && !strings.ContainsRune("-_.:", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/thansetan/berak/model"
+)
+
+const SessionCookieName = "berak_session"
+
+type sessionContextKey struct{}
+
+type SessionGetter interface {
+	Get(ctx context.Context, id string) (model.Session, bool, error)
+}
+
+type Session struct {
+	getter SessionGetter
+	logger *slog.Logger
+}
+
+func NewSession(getter SessionGetter, logger *slog.Logger) *Session {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Session{getter, logger}
+}
+
+// Handle loads the session of the request's cookie, if any, into its context.
+func (s *Session) Handle(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(SessionCookieName)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sess, ok, err := s.getter.Get(r.Context(), cookie.Value)
+		if err != nil {
+			s.logger.ErrorContext(r.Context(), "failed to get session!", "error", err, "remote_addr", r.RemoteAddr)
+		}
+		if ok {
+			r = r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, sess))
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+func SessionFromContext(ctx context.Context) (model.Session, bool) {
+	sess, ok := ctx.Value(sessionContextKey{}).(model.Session)
+	return sess, ok
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thansetan/berak/model"
+)
+
+const ShareCookieName = "berak_share"
+
+// ExportPath is the only export that a share link can grant access to, as it
+// can be limited to the link's range.
+const ExportPath = "/api/v1/export"
+
+// sharePathPrefix starts the paths opening a share link, which end with its
+// token.
+const sharePathPrefix = "/share/"
+
+type shareContextKey struct{}
+
+type ShareVerifier interface {
+	Verify(ctx context.Context, token string) (model.ShareLink, bool, error)
+}
+
+var monthPathRe = regexp.MustCompile(`^/([0-9]+)/([0-9]+)$`)
+
+// redactPath hides the token of a share link opened at path, so it's not
+// written wherever requests are logged or traced.
+func redactPath(path string) string {
+	if strings.HasPrefix(path, sharePathPrefix) {
+		return sharePathPrefix + "{token}"
+	}
+	return path
+}
+
+// ShareFromContext returns the share link that granted access to the request,
+// if any.
+func ShareFromContext(ctx context.Context) (model.ShareLink, bool) {
+	l, ok := ctx.Value(shareContextKey{}).(model.ShareLink)
+	return l, ok
+}
+
+// shareCovers reports whether r only reads what l shares: a month page with
+// shared days, its live updates, or an export within the range.
+func shareCovers(l model.ShareLink, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	q := r.URL.Query()
+	switch r.URL.Path {
+	case "/sse":
+		return q.Get("period") == "daily" && coversMonth(l, q.Get("year"), q.Get("month"))
+	case ExportPath:
+		from, err := time.Parse("2006-01-02", q.Get("from"))
+		if err != nil {
+			return false
+		}
+		to, err := time.Parse("2006-01-02", q.Get("to"))
+		return err == nil && l.CoversRange(from, to)
+	}
+	m := monthPathRe.FindStringSubmatch(r.URL.Path)
+	return m != nil && coversMonth(l, m[1], m[2])
+}
+
+func coversMonth(l model.ShareLink, yearStr, monthStr string) bool {
+	year, err := strconv.Atoi(yearStr)
+	if err != nil {
+		return false
+	}
+	month, err := strconv.Atoi(monthStr)
+	if err != nil || month < 1 || month > 12 {
+		return false
+	}
+	return l.CoversMonth(year, time.Month(month))
+}
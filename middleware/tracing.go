@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// untracedPaths aren't worth a span: event streams stay open for as long as
+// the page does, and metrics are scraped and probes sent every few seconds.
+var untracedPaths = []string{"/sse", "/metrics", "/healthcheck", "/livez", "/readyz"}
+
+type Tracing struct{}
+
+func NewTracing() *Tracing {
+	return &Tracing{}
+}
+
+// Handle starts a span for every request, continuing the trace of the caller
+// if any. It has to wrap the router, which names the span with Route.
+func (t *Tracing) Handle(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "HTTP", otelhttp.WithFilter(func(r *http.Request) bool {
+		for _, path := range untracedPaths {
+			if r.URL.Path == path {
+				return false
+			}
+		}
+		return true
+	}), otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method
+	}))
+}
+
+// Route is a router middleware naming the span of the request after the
+// path template of the matched route.
+func (t *Tracing) Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		if current := mux.CurrentRoute(r); current != nil && span.IsRecording() {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				span.SetName(r.Method + " " + tmpl)
+				span.SetAttributes(attribute.String("http.route", tmpl))
+			}
+		}
+		if path := redactPath(r.URL.Path); path != r.URL.Path && span.IsRecording() {
+			span.SetAttributes(attribute.String("url.path", path))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
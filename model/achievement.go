@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type Badge struct {
+	ID          string `json:"id"`
+	Emoji       string `json:"emoji"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Achievement struct {
+	Badge
+	UnlockedAt time.Time `json:"unlocked_at"`
+}
+
+func (a Achievement) IsUnlocked() bool {
+	return !a.UnlockedAt.IsZero()
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+type AnomalyKind string
+
+const (
+	AnomalyKindHighCount AnomalyKind = "high_count"
+	AnomalyKindLowCount  AnomalyKind = "low_count"
+	AnomalyKindLongGap   AnomalyKind = "long_gap"
+)
+
+type Anomaly struct {
+	Date  time.Time   `json:"date"`
+	Kind  AnomalyKind `json:"kind"`
+	Count int         `json:"count"`
+	// Baseline and ZScore are only set for count anomalies.
+	Baseline float64 `json:"baseline,omitempty"`
+	ZScore   float64 `json:"z_score,omitempty"`
+	// Gap is only set for long gap anomalies, ending on Date.
+	Gap *LongestDayWithoutPoop `json:"gap,omitempty"`
+}
+
+func (a Anomaly) String() string {
+	switch a.Kind {
+	case AnomalyKindHighCount:
+		return fmt.Sprintf("unusually many 💩s: %d vs. ~%.1f a day", a.Count, a.Baseline)
+	case AnomalyKindLowCount:
+		return fmt.Sprintf("unusually few 💩s: %d vs. ~%.1f a day", a.Count, a.Baseline)
+	case AnomalyKindLongGap:
+		// a coarse gap within a single day has no length left to tell.
+		if a.Gap != nil && a.Gap.Duration() > 0 {
+			return fmt.Sprintf("unusually long no-💩 streak: %s", a.Gap)
+		}
+		return "unusually long no-💩 streak"
+	}
+	return string(a.Kind)
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type Forecast struct {
+	Expected time.Time `json:"expected"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	// SampleSize is the number of past intervals the forecast is based on.
+	SampleSize int `json:"sample_size"`
+}
+
+func (f Forecast) IsEmpty() bool {
+	return f.Expected.IsZero()
+}
+
+func (f Forecast) String() string {
+	if f.From.Equal(f.To) {
+		return f.Expected.Format("02 January 15:04")
+	}
+	if f.From.YearDay() == f.To.YearDay() && f.From.Year() == f.To.Year() {
+		return f.From.Format("02 January 15:04") + "–" + f.To.Format("15:04")
+	}
+	return f.From.Format("02 January 15:04") + " – " + f.To.Format("02 January 15:04")
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+type GoalKind string
+
+const (
+	// GoalKindDaily is reached by dropping at least Target 💩s today.
+	GoalKindDaily GoalKind = "daily"
+	// GoalKindMonthly is reached by dropping at least Target 💩s this month.
+	GoalKindMonthly GoalKind = "monthly"
+	// GoalKindStreak is reached by keeping a 💩 streak of at least Target days.
+	GoalKindStreak GoalKind = "streak"
+)
+
+func (k GoalKind) IsValid() bool {
+	switch k {
+	case GoalKindDaily, GoalKindMonthly, GoalKindStreak:
+		return true
+	}
+	return false
+}
+
+type Goal struct {
+	ID        int64     `json:"id"`
+	Kind      GoalKind  `json:"kind"`
+	Target    int       `json:"target"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (g Goal) String() string {
+	switch g.Kind {
+	case GoalKindDaily:
+		return fmt.Sprintf("%d 💩%s a day", g.Target, plural(g.Target))
+	case GoalKindMonthly:
+		return fmt.Sprintf("%d 💩%s a month", g.Target, plural(g.Target))
+	case GoalKindStreak:
+		return fmt.Sprintf("%d day%s 💩 streak", g.Target, plural(g.Target))
+	}
+	return string(g.Kind)
+}
+
+type GoalProgress struct {
+	Goal
+	Current int `json:"current"`
+}
+
+func (g GoalProgress) IsAchieved() bool {
+	return g.Current >= g.Target
+}
+
+// Percentage returns how far the goal is reached, capped at 100.
+func (g GoalProgress) Percentage() int {
+	if g.Target < 1 || g.IsAchieved() {
+		return 100
+	}
+	return g.Current * 100 / g.Target
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
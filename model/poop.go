@@ -9,6 +9,12 @@ import (
 type Data struct {
 	TableData
 	Statistics
+	Records      Records
+	Achievements []Achievement
+	Years        []YearSummary
+	Session      Session
+	// Shared is set when the page is only viewed through a share link.
+	Shared  bool
 	Year    int
 	Month   int
 	BaseURL string
@@ -21,31 +27,51 @@ type TableData struct {
 }
 
 type Statistics struct {
-	LastPoopAt            time.Time
-	LongestDayWithoutPoop LongestDayWithoutPoop
-	LongestPoopStreak     PoopStreak
-	CurrentStreak         PoopStreak
-	MostPoopInADay        MostPoopInADate
-	MostPoopInAMonth      MostPoopInADate
+	LastPoopAt            time.Time             `json:"last_poop_at"`
+	LongestDayWithoutPoop LongestDayWithoutPoop `json:"longest_day_without_poop"`
+	OngoingDrought        LongestDayWithoutPoop `json:"ongoing_drought"`
+	LongestPoopStreak     PoopStreak            `json:"longest_poop_streak"`
+	CurrentStreak         PoopStreak            `json:"current_streak"`
+	MostPoopInADay        MostPoopInADate       `json:"most_poop_in_a_day"`
+	MostPoopInAMonth      MostPoopInADate       `json:"most_poop_in_a_month"`
+	Goals                 []GoalProgress        `json:"goals,omitempty"`
+	NextPoop              Forecast              `json:"next_poop"`
+	// Coarse is set when the times of day have been stripped.
+	IsCoarse bool `json:"coarse"`
 }
 
 type AggData struct {
-	Period int
-	Count  int
+	Period    int       `json:"period"`
+	Count     int       `json:"count"`
+	Anomalies []Anomaly `json:"anomalies,omitempty"`
+}
+
+type Records struct {
+	LongestPoopStreaks     []PoopStreak            `json:"longest_poop_streaks"`
+	LongestDaysWithoutPoop []LongestDayWithoutPoop `json:"longest_days_without_poop"`
+	MostPoopDays           []MostPoopInADate       `json:"most_poop_days"`
+	MostPoopMonths         []MostPoopInADate       `json:"most_poop_months"`
 }
 
 type LongestDayWithoutPoop struct {
-	StartTime time.Time
-	EndTime   time.Time
+	Rank      int       `json:"rank,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// Ongoing is true when EndTime is the current time rather than a recorded 💩.
+	Ongoing bool `json:"ongoing"`
 }
 
 func (l LongestDayWithoutPoop) IsEmpty() bool {
-	return (l.StartTime.IsZero() || l.EndTime.IsZero()) || (l.EndTime.Sub(l.StartTime) < time.Minute)
+	return (l.StartTime.IsZero() || l.EndTime.IsZero()) || (l.Duration() < time.Minute)
+}
+
+func (l LongestDayWithoutPoop) Duration() time.Duration {
+	return l.EndTime.Sub(l.StartTime)
 }
 
 func (l LongestDayWithoutPoop) String() string {
 	var sb strings.Builder
-	timeDiff := l.EndTime.Sub(l.StartTime)
+	timeDiff := l.Duration()
 	dayDiff := int(timeDiff.Hours()) / 24
 	hourDiff := int(timeDiff.Hours()) - 24*dayDiff
 	minuteDiff := int(timeDiff.Minutes()) - 24*dayDiff*60 - 60*hourDiff
@@ -82,10 +108,11 @@ func (l LongestDayWithoutPoop) String() string {
 }
 
 type MostPoopInADate struct {
-	Year  int
-	Month int
-	Day   int
-	Count int
+	Rank  int `json:"rank,omitempty"`
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day,omitempty"`
+	Count int `json:"count"`
 }
 
 func (m MostPoopInADate) Path() string {
@@ -97,10 +124,21 @@ func (m MostPoopInADate) IsEmpty() bool {
 }
 
 type PoopStreak struct {
-	StartDate, EndDate  time.Time
-	DayCount, PoopCount int
+	Rank      int       `json:"rank,omitempty"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	DayCount  int       `json:"day_count"`
+	PoopCount int       `json:"poop_count"`
 }
 
 func (l PoopStreak) IsEmpty() bool {
 	return l.DayCount < 2
 }
+
+type YearSummary struct {
+	Year int
+	// Months holds the 💩 count of every month, January first.
+	Months            [12]int
+	Total             int
+	LongestPoopStreak PoopStreak
+}
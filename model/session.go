@@ -0,0 +1,32 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+type Session struct {
+	Scopes    []Scope
+	CSRFToken string
+	ExpiresAt time.Time
+}
+
+func (s Session) IsZero() bool {
+	return s.ExpiresAt.IsZero()
+}
+
+func (s Session) HasScope(scope Scope) bool {
+	return slices.Contains(s.Scopes, ScopeAdmin) || slices.Contains(s.Scopes, scope)
+}
+
+func (s Session) CanWrite() bool {
+	return s.HasScope(ScopeWrite)
+}
+
+func (s Session) CanDelete() bool {
+	return s.HasScope(ScopeDelete)
+}
+
+func (s Session) CanExport() bool {
+	return s.HasScope(ScopeExport)
+}
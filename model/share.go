@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+type ShareLink struct {
+	ID int64 `json:"id"`
+	// From and To are the first and last day, inclusive, that can be viewed.
+	From      time.Time  `json:"from"`
+	To        time.Time  `json:"to"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (l ShareLink) IsActive(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
+
+// CoversMonth reports whether any day of the month is shared.
+func (l ShareLink) CoversMonth(year int, month time.Month) bool {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	return !first.After(l.To) && !last.Before(l.From)
+}
+
+// CoversRange reports whether every day from from until to is shared.
+func (l ShareLink) CoversRange(from, to time.Time) bool {
+	return !from.Before(l.From) && !to.After(l.To)
+}
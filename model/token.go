@@ -0,0 +1,45 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+type Scope string
+
+const (
+	ScopeWrite  Scope = "write"
+	ScopeDelete Scope = "delete"
+	ScopeExport Scope = "export"
+	// ScopeMetrics only grants scraping the metrics, for the token of a
+	// Prometheus scraper.
+	ScopeMetrics Scope = "metrics"
+	// ScopeAdmin grants every other scope, and managing API tokens.
+	ScopeAdmin Scope = "admin"
+)
+
+func (s Scope) IsValid() bool {
+	switch s {
+	case ScopeWrite, ScopeDelete, ScopeExport, ScopeMetrics, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []Scope    `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t APIToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, ScopeAdmin) || slices.Contains(t.Scopes, scope)
+}
+
+func (t APIToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"time"
+
+	"github.com/thansetan/berak/helper"
+)
+
+type Visibility string
+
+const (
+	// VisibilityPrivate hides every page from anonymous viewers.
+	VisibilityPrivate Visibility = "private"
+	// VisibilityCoarse shows anonymous viewers dates but never times.
+	VisibilityCoarse Visibility = "coarse"
+	VisibilityPublic Visibility = "public"
+)
+
+func (v Visibility) IsValid() bool {
+	switch v {
+	case VisibilityPrivate, VisibilityCoarse, VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+// Coarse strips the time of day from every timestamp and drops the next 💩
+// forecast, as it would give them away.
+func (s Statistics) Coarse() Statistics {
+	s.IsCoarse = true
+	s.LastPoopAt = truncateToDay(s.LastPoopAt)
+	s.LongestDayWithoutPoop = s.LongestDayWithoutPoop.Coarse()
+	s.OngoingDrought = s.OngoingDrought.Coarse()
+	s.NextPoop = Forecast{}
+	return s
+}
+
+func (l LongestDayWithoutPoop) Coarse() LongestDayWithoutPoop {
+	l.StartTime = truncateToDay(l.StartTime)
+	l.EndTime = truncateToDay(l.EndTime)
+	return l
+}
+
+func (r Records) Coarse() Records {
+	droughts := make([]LongestDayWithoutPoop, len(r.LongestDaysWithoutPoop))
+	for i, l := range r.LongestDaysWithoutPoop {
+		droughts[i] = l.Coarse()
+	}
+	r.LongestDaysWithoutPoop = droughts
+	return r
+}
+
+func (a Achievement) Coarse() Achievement {
+	a.UnlockedAt = truncateToDay(a.UnlockedAt)
+	return a
+}
+
+func (a Anomaly) Coarse() Anomaly {
+	if a.Gap != nil {
+		gap := a.Gap.Coarse()
+		a.Gap = &gap
+	}
+	return a
+}
+
+// Coarse strips the time of day from the gaps of the anomalies of every period.
+func (t TableData) Coarse() TableData {
+	data := make([]AggData, len(t.Data))
+	for i, d := range t.Data {
+		if len(d.Anomalies) > 0 {
+			anomalies := make([]Anomaly, len(d.Anomalies))
+			for j, a := range d.Anomalies {
+				anomalies[j] = a.Coarse()
+			}
+			d.Anomalies = anomalies
+		}
+		data[i] = d
+	}
+	t.Data = data
+	return t
+}
+
+// truncateToDay keeps zero times zero, so they are still treated as missing.
+func truncateToDay(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return helper.TruncateToDay(t)
+}
@@ -0,0 +1,117 @@
+// Package ratelimit decides whether a request may go through given how many
+// were made recently under the same key.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+)
+
+// Limit allows Requests requests per Window.
+type Limit struct {
+	Requests uint64
+	Window   time.Duration
+}
+
+type Result struct {
+	Allowed bool
+	Limit   uint64
+	// Remaining is how many more requests would be allowed right now.
+	Remaining uint64
+	// Reset is how long until the whole limit is available again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed, 0 if
+	// this one was.
+	RetryAfter time.Duration
+}
+
+// Limiter counts the requests made under a key against a limit, which is given
+// with every request so it can be changed at any time. A limiter keeping its
+// counts in a shared store lets several instances of the server share limits.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// New returns a MemoryLimiter using algorithm.
+func New(algorithm string) (*MemoryLimiter, error) {
+	switch algorithm {
+	case AlgorithmTokenBucket:
+		return newMemoryLimiter(func() bucket { return new(tokenBucket) }), nil
+	case AlgorithmSlidingWindow:
+		return newMemoryLimiter(func() bucket { return new(slidingWindow) }), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit algorithm %q", algorithm)
+}
+
+// bucket holds the count of a key.
+type bucket interface {
+	// take counts a request made at now if limit allows it.
+	take(now time.Time, limit Limit) Result
+	// idle tells whether the bucket is back to its initial state at now, so it
+	// can be forgotten.
+	idle(now time.Time) bool
+}
+
+// MemoryLimiter keeps the counts in memory, so they're only those of this
+// instance. Run has to be running to forget the keys no longer used.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]bucket
+	newBucket func() bucket
+	// now is replaced by tests.
+	now func() time.Time
+}
+
+func newMemoryLimiter(newBucket func() bucket) *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]bucket),
+		newBucket: newBucket,
+		now:       time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Result, error) {
+	if limit.Requests == 0 || limit.Window <= 0 {
+		return Result{}, fmt.Errorf("invalid limit of %d requests per %s", limit.Requests, limit.Window)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = l.newBucket()
+		l.buckets[key] = b
+	}
+	return b.take(l.now(), limit), nil
+}
+
+// Run forgets the idle keys every interval until ctx is done.
+func (l *MemoryLimiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.cleanup()
+		}
+	}
+}
+
+func (l *MemoryLimiter) cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for key, b := range l.buckets {
+		if b.idle(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
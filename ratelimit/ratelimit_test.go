@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// base is aligned to the windows used below, as sliding windows are.
+var base = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type step struct {
+	// at is the time of the request since base.
+	at    time.Duration
+	limit Limit
+	want  Result
+}
+
+func runSteps(t *testing.T, algorithm string, steps []step) {
+	t.Helper()
+	l, err := New(algorithm)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for i, s := range steps {
+		l.now = func() time.Time { return base.Add(s.at) }
+		got, err := l.Allow(context.Background(), "key", s.limit)
+		if err != nil {
+			t.Fatalf("step #%d: Allow: %v", i, err)
+		}
+		if got != s.want {
+			t.Errorf("step #%d at %s: Allow = %+v, want %+v", i, s.at, got, s.want)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	three := Limit{Requests: 3, Window: 30 * time.Second}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "burst then refill",
+			steps: []step{
+				{0, three, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 10 * time.Second}},
+				{0, three, Result{Allowed: true, Limit: 3, Remaining: 1, Reset: 20 * time.Second}},
+				{0, three, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 30 * time.Second}},
+				{0, three, Result{Limit: 3, Remaining: 0, Reset: 30 * time.Second, RetryAfter: 10 * time.Second}},
+				{5 * time.Second, three, Result{Limit: 3, Remaining: 0, Reset: 25 * time.Second, RetryAfter: 5 * time.Second}},
+				{10 * time.Second, three, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 30 * time.Second}},
+				// a long pause only refills up to the limit.
+				{time.Hour, three, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 10 * time.Second}},
+			},
+		},
+		{
+			name: "lowered limit",
+			steps: []step{
+				{0, three, Result{Allowed: true, Limit: 3, Remaining: 2, Reset: 10 * time.Second}},
+				// the 2 tokens left are cut down to the new limit of 1.
+				{0, Limit{1, 10 * time.Second}, Result{Allowed: true, Limit: 1, Remaining: 0, Reset: 10 * time.Second}},
+				{0, Limit{1, 10 * time.Second}, Result{Limit: 1, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 10 * time.Second}},
+			},
+		},
+		{
+			name: "raised limit",
+			steps: []step{
+				{0, Limit{1, 10 * time.Second}, Result{Allowed: true, Limit: 1, Remaining: 0, Reset: 10 * time.Second}},
+				// the bucket refills towards the new limit from what was left.
+				{10 * time.Second, three, Result{Allowed: true, Limit: 3, Remaining: 0, Reset: 30 * time.Second}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runSteps(t, AlgorithmTokenBucket, tt.steps)
+		})
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	two := Limit{Requests: 2, Window: 10 * time.Second}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "weighs the previous window",
+			steps: []step{
+				{0, two, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 20 * time.Second}},
+				{time.Second, two, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 19 * time.Second}},
+				// the 2 have to be half out of the window for one more.
+				{2 * time.Second, two, Result{Limit: 2, Remaining: 0, Reset: 18 * time.Second, RetryAfter: 13 * time.Second}},
+				// half the previous window still counts: 2*0.5 + 0.
+				{15 * time.Second, two, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 15 * time.Second}},
+				// 2*0.4 + 1, one more is too many until the previous
+				// window is out.
+				{16 * time.Second, two, Result{Limit: 2, Remaining: 0, Reset: 14 * time.Second, RetryAfter: 4 * time.Second}},
+				{20 * time.Second, two, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 20 * time.Second}},
+			},
+		},
+		{
+			name: "forgets windows older than the previous one",
+			steps: []step{
+				{0, two, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 20 * time.Second}},
+				{0, two, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 20 * time.Second}},
+				{20 * time.Second, two, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 20 * time.Second}},
+			},
+		},
+		{
+			name: "denied by the previous window only",
+			steps: []step{
+				{9 * time.Second, two, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 11 * time.Second}},
+				{9 * time.Second, two, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 11 * time.Second}},
+				// 2*0.9 + 0: one more once half the previous window slid
+				// out, at 15s.
+				{11 * time.Second, two, Result{Limit: 2, Remaining: 0, Reset: 9 * time.Second, RetryAfter: 4 * time.Second}},
+				{12 * time.Second, two, Result{Limit: 2, Remaining: 0, Reset: 8 * time.Second, RetryAfter: 3 * time.Second}},
+				{15 * time.Second, two, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 15 * time.Second}},
+			},
+		},
+		{
+			name: "window change starts over",
+			steps: []step{
+				{0, two, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 20 * time.Second}},
+				{0, two, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 20 * time.Second}},
+				{0, Limit{2, 20 * time.Second}, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 40 * time.Second}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runSteps(t, AlgorithmSlidingWindow, tt.steps)
+		})
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmTokenBucket, AlgorithmSlidingWindow} {
+		t.Run(algorithm, func(t *testing.T) {
+			l, err := New(algorithm)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			limit := Limit{Requests: 2, Window: 10 * time.Second}
+			l.now = func() time.Time { return base }
+			_, err = l.Allow(context.Background(), "key", limit)
+			if err != nil {
+				t.Fatalf("Allow: %v", err)
+			}
+
+			l.cleanup()
+			if len(l.buckets) != 1 {
+				t.Fatal("a key in use was forgotten")
+			}
+			l.now = func() time.Time { return base.Add(time.Minute) }
+			l.cleanup()
+			if len(l.buckets) != 0 {
+				t.Error("an idle key was kept")
+			}
+		})
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	_, err := New("leaky_bucket")
+	if err == nil {
+		t.Error("New accepted an unknown algorithm")
+	}
+	l, err := New(AlgorithmTokenBucket)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	_, err = l.Allow(context.Background(), "key", Limit{Requests: 0, Window: time.Second})
+	if err == nil {
+		t.Error("Allow accepted a limit of no request")
+	}
+}
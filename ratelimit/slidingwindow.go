@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// slidingWindow counts the requests of the current and the previous fixed
+// windows, and estimates those made in the last Window by weighing the
+// previous count by how much of the previous window is still in it. Unlike a
+// fixed window, it doesn't allow twice the limit around window boundaries.
+type slidingWindow struct {
+	start         time.Time
+	prev, current uint64
+	window        time.Duration
+}
+
+func (b *slidingWindow) take(now time.Time, limit Limit) Result {
+	w := limit.Window
+	if b.window != w {
+		// the window was changed, start counting again.
+		b.start, b.prev, b.current, b.window = now.Truncate(w), 0, 0, w
+	}
+	if elapsed := now.Sub(b.start); elapsed >= w {
+		if elapsed < 2*w {
+			b.prev = b.current
+		} else {
+			b.prev = 0
+		}
+		b.current = 0
+		b.start = now.Truncate(w)
+	}
+
+	requests := float64(limit.Requests)
+	prevWeight := 1 - float64(now.Sub(b.start))/float64(w)
+	estimate := float64(b.prev)*prevWeight + float64(b.current)
+
+	result := Result{Limit: limit.Requests}
+	if estimate+1 <= requests {
+		b.current++
+		estimate++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = b.retryAfter(now, requests)
+	}
+	result.Remaining = uint64(math.Max(0, requests-math.Ceil(estimate)))
+	if b.current > 0 {
+		result.Reset = b.start.Add(2 * w).Sub(now)
+	} else if b.prev > 0 {
+		result.Reset = b.start.Add(w).Sub(now)
+	}
+	return result
+}
+
+// retryAfter is how long until the estimate drops enough for one more request.
+func (b *slidingWindow) retryAfter(now time.Time, requests float64) time.Duration {
+	w := float64(b.window)
+	var at time.Time
+	if float64(b.current)+1 <= requests {
+		// enough of the previous window has to slide out.
+		share := 1 - (requests-1-float64(b.current))/float64(b.prev)
+		at = b.start.Add(time.Duration(share * w))
+	} else {
+		// the current window becomes the previous one, and enough of it has
+		// to slide out.
+		share := 1 - (requests-1)/float64(b.current)
+		at = b.start.Add(b.window).Add(time.Duration(share * w))
+	}
+	return max(at.Sub(now), 0)
+}
+
+func (b *slidingWindow) idle(now time.Time) bool {
+	return !now.Before(b.start.Add(2 * b.window))
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// tokenBucket holds up to Requests tokens, refilled at Requests per Window,
+// and every request takes one. It allows bursts of up to the whole limit, then
+// spreads the requests evenly.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket is full again.
+	full time.Time
+}
+
+func (b *tokenBucket) take(now time.Time, limit Limit) Result {
+	capacity := float64(limit.Requests)
+	perToken := limit.Window / time.Duration(limit.Requests)
+	if b.last.IsZero() {
+		b.tokens = capacity
+	} else {
+		// the limit may have been lowered below the tokens left.
+		b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	}
+	b.last = now
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	result.Remaining = uint64(b.tokens)
+	result.Reset = time.Duration((capacity - b.tokens) * float64(perToken))
+	b.full = now.Add(result.Reset)
+	return result
+}
+
+func (b *tokenBucket) idle(now time.Time) bool {
+	return !now.Before(b.full)
+}
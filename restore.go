@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/thansetan/berak/backup"
+	"github.com/thansetan/berak/config"
+	"github.com/thansetan/berak/db"
+)
+
+// runRestore replaces the database with the backup at args[0]. The server
+// has to be stopped first, as it wouldn't notice the database being swapped.
+func runRestore(logger *slog.Logger, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: berak restore <file>")
+		return 2
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		logger.Error("failed to read backup!", "error", err)
+		return 1
+	}
+
+	cfg, err := config.Load(os.Getenv(config.PathEnv))
+	if err != nil {
+		logger.Error("invalid configuration!", "error", err)
+		return 1
+	}
+	conn, err := db.NewConn(cfg.DataSourceName)
+	if err != nil {
+		logger.Error("failed to establish database connection!", "error", err)
+		return 1
+	}
+	defer conn.Close()
+
+	// keep every snapshot, the safety snapshot shouldn't push out an older one.
+	svc := backup.NewService(conn, cfg.BackupDir(), 0, cfg.Backup.Passphrase, logger)
+	err = svc.Restore(context.Background(), data)
+	if err != nil {
+		logger.Error("failed to restore backup!", "error", err)
+		return 1
+	}
+	logger.Info("backup restored!", "file", args[0])
+	return 0
+}
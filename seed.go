@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thansetan/berak/berak"
+	"github.com/thansetan/berak/config"
+	"github.com/thansetan/berak/db"
+	"github.com/thansetan/berak/helper"
+)
+
+// defaultHourWeights is how likely a 💩 is to drop at every hour of the day:
+// mostly in the morning, with smaller peaks after lunch and dinner.
+var defaultHourWeights = []float64{1, 0, 0, 0, 1, 3, 8, 12, 10, 6, 4, 3, 4, 5, 3, 2, 2, 3, 4, 5, 4, 3, 2, 1}
+
+const (
+	// seedMaxGapDays is the longest gap without 💩 that starts on its own.
+	seedMaxGapDays  = 4
+	seedMeanGapDays = (1 + seedMaxGapDays) / 2.0
+)
+
+type seedConfig struct {
+	days        int
+	rate        float64
+	gapChance   float64
+	hourWeights []float64
+	until       time.Time
+}
+
+// runSeed fills an empty database with synthetic 💩s, the same ones for the
+// same flags as long as -until is pinned. By default they end now, so they
+// depend on when it's run.
+func runSeed(logger *slog.Logger, args []string) int {
+	var (
+		cfg          seedConfig
+		seed         uint64
+		hours, until string
+		force        bool
+		fs           = flag.NewFlagSet("seed", flag.ContinueOnError)
+	)
+	fs.IntVar(&cfg.days, "days", 365, "number of days to generate, ending at -until")
+	fs.Float64Var(&cfg.rate, "rate", 1.3, "average number of 💩s per day, gaps included")
+	fs.Float64Var(&cfg.gapChance, "gap", 0.05, fmt.Sprintf("chance of a day starting a gap of 1 to %d days without 💩", seedMaxGapDays))
+	fs.Uint64Var(&seed, "seed", 1, "seed of the random generator")
+	fs.StringVar(&hours, "hours", "", "24 comma-separated weights of the hours of the day (default mostly mornings)")
+	fs.StringVar(&until, "until", "", "last day to generate as YYYY-MM-DD, before today, for the same 💩s on every run (default today until now)")
+	fs.BoolVar(&force, "force", false, "add to a database that already has 💩s")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: berak seed [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || cfg.days < 1 || cfg.rate <= 0 || cfg.gapChance < 0 || cfg.gapChance >= 1 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg.hourWeights = defaultHourWeights
+	if hours != "" {
+		var err error
+		cfg.hourWeights, err = parseHourWeights(hours)
+		if err != nil {
+			logger.Error("invalid hour weights!", "error", err)
+			return 2
+		}
+	}
+
+	appCfg, err := config.Load(os.Getenv(config.PathEnv))
+	if err != nil {
+		logger.Error("invalid configuration!", "error", err)
+		return 1
+	}
+	offset := appCfg.Offset()
+	now := time.Now().UTC()
+	today := helper.TruncateToDay(offset.Apply(now))
+	cfg.until = today
+	if until != "" {
+		cfg.until, err = time.Parse(time.DateOnly, until)
+		if err != nil || !cfg.until.Before(today) {
+			logger.Error("invalid until date, it has to be a day before today!", "until", until)
+			return 2
+		}
+	}
+
+	conn, err := db.NewConn(appCfg.DataSourceName)
+	if err != nil {
+		logger.Error("failed to establish database connection!", "error", err)
+		return 1
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	repo := berak.NewRepo(conn)
+	n, err := repo.Count(ctx)
+	if err != nil {
+		logger.Error("failed to count poops!", "error", err)
+		return 1
+	}
+	if n > 0 && !force {
+		logger.Error("database isn't empty, use -force to add to it anyway!", "count", n)
+		return 1
+	}
+
+	// the generated times are local, the stored ones are UTC.
+	utcOffset := offset.Apply(now).Sub(now)
+	var added int
+	for _, t := range generateSeed(cfg, rand.New(rand.NewPCG(seed, seed))) {
+		t = t.Add(-utcOffset)
+		// only today's may be in the future, a pinned until is in the past.
+		if t.After(now) {
+			break
+		}
+		err = repo.AddWithDate(ctx, t)
+		if err != nil {
+			logger.Error("failed to add poop!", "error", err)
+			return 1
+		}
+		added++
+	}
+	logger.Info("database seeded!", "count", added, "days", cfg.days, "seed", seed)
+	return 0
+}
+
+func parseHourWeights(s string) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 24 {
+		return nil, fmt.Errorf("got %d weights, want 24", len(parts))
+	}
+	weights := make([]float64, 0, 24)
+	var total float64
+	for _, p := range parts {
+		w, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %q", p)
+		}
+		weights = append(weights, w)
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("every weight is 0")
+	}
+	return weights, nil
+}
+
+// generateSeed returns sorted local times of synthetic 💩s. Any day may start
+// a gap without 💩, every other day gets a Poisson distributed number of 💩s
+// at hours picked by their weight.
+func generateSeed(cfg seedConfig, rng *rand.Rand) []time.Time {
+	// make up for the gaps so the average over all days stays at rate.
+	gapShare := cfg.gapChance * seedMeanGapDays / (1 + cfg.gapChance*seedMeanGapDays)
+	dayRate := cfg.rate / (1 - gapShare)
+
+	var (
+		times   []time.Time
+		gapLeft int
+	)
+	first := cfg.until.AddDate(0, 0, -cfg.days+1)
+	for d := range cfg.days {
+		if gapLeft > 0 {
+			gapLeft--
+			continue
+		}
+		if rng.Float64() < cfg.gapChance {
+			gapLeft = rng.IntN(seedMaxGapDays)
+			continue
+		}
+
+		day := first.AddDate(0, 0, d)
+		var dayTimes []time.Time
+		for range poisson(rng, dayRate) {
+			offset := time.Duration(pickWeighted(rng, cfg.hourWeights))*time.Hour + time.Duration(rng.IntN(3600))*time.Second
+			dayTimes = append(dayTimes, day.Add(offset))
+		}
+		slices.SortFunc(dayTimes, time.Time.Compare)
+		times = append(times, dayTimes...)
+	}
+
+	return times
+}
+
+// poisson returns a Poisson distributed number with mean lambda.
+func poisson(rng *rand.Rand, lambda float64) int {
+	l, k, p := math.Exp(-lambda), 0, 1.0
+	for {
+		p *= rng.Float64()
+		if p <= l {
+			return k
+		}
+		k++
+	}
+}
+
+// pickWeighted returns an index of weights, each as likely as its weight.
+func pickWeighted(rng *rand.Rand, weights []float64) int {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	r := rng.Float64() * total
+	for i, w := range weights {
+		r -= w
+		if r < 0 {
+			return i
+		}
+	}
+	return len(weights) - 1
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGenerateSeedReproducible(t *testing.T) {
+	cfg := seedConfig{
+		days:        90,
+		rate:        1.3,
+		gapChance:   0.05,
+		hourWeights: defaultHourWeights,
+		until:       time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC),
+	}
+	generate := func(seed uint64) []time.Time {
+		return generateSeed(cfg, rand.New(rand.NewPCG(seed, seed)))
+	}
+
+	first := generate(42)
+	if len(first) == 0 {
+		t.Fatal("generateSeed generated nothing")
+	}
+	if !slices.Equal(first, generate(42)) {
+		t.Error("the same seed generated different 💩s")
+	}
+	if slices.Equal(first, generate(43)) {
+		t.Error("different seeds generated the same 💩s")
+	}
+	if !slices.IsSortedFunc(first, time.Time.Compare) {
+		t.Error("generated 💩s aren't sorted")
+	}
+	firstDay, lastDay := cfg.until.AddDate(0, 0, -cfg.days+1), cfg.until.AddDate(0, 0, 1)
+	if first[0].Before(firstDay) || !first[len(first)-1].Before(lastDay) {
+		t.Errorf("generated 💩s from %s to %s, want within %s and %s", first[0], first[len(first)-1], firstDay, lastDay)
+	}
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"crypto/subtle"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/thansetan/berak/config"
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/middleware"
+)
+
+// loginCSRFCookieName holds the double-submitted CSRF token of the login form,
+// as there's no session to store it in yet.
+const loginCSRFCookieName = "berak_login_csrf"
+
+type controller struct {
+	tmpl   *template.Template
+	logger *slog.Logger
+	svc    *sessionService
+	cfg    config.Config
+}
+
+func NewController(svc *sessionService, tmpl *template.Template, cfg config.Config, logger *slog.Logger) *controller {
+	return &controller{tmpl, logger, svc, cfg}
+}
+
+type loginPage struct {
+	CSRFToken string
+	Error     string
+	BaseURL   string
+}
+
+func (c *controller) LoginPage(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.SessionFromContext(r.Context()); ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	c.renderLogin(w, r, http.StatusOK, "")
+}
+
+func (c *controller) Login(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(loginCSRFCookieName)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostFormValue("csrf_token"))) != 1 {
+		c.renderLogin(w, r, http.StatusForbidden, "Your login form expired, please try again.")
+		return
+	}
+
+	id, sess, err := c.svc.Login(r.Context(), r.PostFormValue("key"))
+	if errors.Is(err, ErrInvalidKey) {
+		c.logger.WarnContext(r.Context(), "login with invalid key", "remote_addr", r.RemoteAddr)
+		c.renderLogin(w, r, http.StatusUnauthorized, "gaboleh 😡")
+		return
+	}
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to log in", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+
+	http.SetCookie(w, helper.NewCookie(loginCSRFCookieName, "", time.Unix(0, 0), c.cfg.SecureCookies()))
+	http.SetCookie(w, helper.NewCookie(middleware.SessionCookieName, id, sess.ExpiresAt, c.cfg.SecureCookies()))
+	c.logger.InfoContext(r.Context(), "logged in!", "remote_addr", r.RemoteAddr)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (c *controller) Logout(w http.ResponseWriter, r *http.Request) {
+	sess, ok := middleware.SessionFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(sess.CSRFToken), []byte(r.PostFormValue("csrf_token"))) != 1 {
+		helper.WriteMessage(w, http.StatusForbidden, "invalid CSRF token 😡")
+		return
+	}
+	cookie, err := r.Cookie(middleware.SessionCookieName)
+	if err == nil {
+		err = c.svc.Logout(r.Context(), cookie.Value)
+		if err != nil {
+			c.logger.ErrorContext(r.Context(), "failed to log out", "error", err.Error(), "remote_addr", r.RemoteAddr)
+			helper.OurFault(w)
+			return
+		}
+	}
+
+	http.SetCookie(w, helper.NewCookie(middleware.SessionCookieName, "", time.Unix(0, 0), c.cfg.SecureCookies()))
+	c.logger.InfoContext(r.Context(), "logged out!", "remote_addr", r.RemoteAddr)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (c *controller) renderLogin(w http.ResponseWriter, r *http.Request, statusCode int, errMsg string) {
+	csrfToken, err := helper.RandomString(32)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to generate csrf token", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	http.SetCookie(w, helper.NewCookie(loginCSRFCookieName, csrfToken, time.Now().Add(time.Hour), c.cfg.SecureCookies()))
+
+	w.WriteHeader(statusCode)
+	err = c.tmpl.ExecuteTemplate(w, "login", loginPage{
+		CSRFToken: csrfToken,
+		Error:     errMsg,
+		BaseURL:   c.cfg.BaseURL,
+	})
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to execute login template", "error", err.Error(), "remote_addr", r.RemoteAddr)
+	}
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/thansetan/berak/db"
+	"github.com/thansetan/berak/model"
+)
+
+type sessionRepository struct {
+	db *db.Conn
+}
+
+func NewRepo(db *db.Conn) *sessionRepository {
+	return &sessionRepository{db}
+}
+
+func (r *sessionRepository) Add(ctx context.Context, idHash string, tokenID int64, s model.Session) error {
+	scopes := make([]string, len(s.Scopes))
+	for i, scope := range s.Scopes {
+		scopes[i] = string(scope)
+	}
+	var tokenIDArg sql.NullInt64
+	if tokenID != 0 {
+		tokenIDArg = sql.NullInt64{Int64: tokenID, Valid: true}
+	}
+	_, err := r.db.ExecContext(ctx, `
+	INSERT INTO sessions(id_hash, token_id, scopes, csrf_token, expires_at, created_at) VALUES(?, ?, ?, ?, ?, ?)`,
+		idHash, tokenIDArg, strings.Join(scopes, ","), s.CSRFToken, s.ExpiresAt, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Get returns an unexpired session, as long as the token it was created with
+// is still active.
+func (r *sessionRepository) Get(ctx context.Context, idHash string, now time.Time) (model.Session, error) {
+	var (
+		s      model.Session
+		scopes string
+	)
+	err := r.db.QueryRowContext(ctx, `
+	SELECT s.scopes, s.csrf_token, s.expires_at
+	FROM sessions s
+	LEFT JOIN api_tokens t ON t.id = s.token_id
+	WHERE s.id_hash = ? AND s.expires_at > ?
+		AND (s.token_id IS NULL OR (t.revoked_at IS NULL AND (t.expires_at IS NULL OR t.expires_at > ?)))`,
+		idHash, now, now).Scan(&scopes, &s.CSRFToken, &s.ExpiresAt)
+	if err != nil {
+		return model.Session{}, err
+	}
+	for _, scope := range strings.Split(scopes, ",") {
+		s.Scopes = append(s.Scopes, model.Scope(scope))
+	}
+
+	return s, nil
+}
+
+func (r *sessionRepository) Delete(ctx context.Context, idHash string) error {
+	_, err := r.db.ExecContext(ctx, `
+	DELETE FROM sessions WHERE id_hash = ?`, idHash)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *sessionRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+	DELETE FROM sessions WHERE expires_at <= ?`, now)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/model"
+)
+
+const sessionDuration = 30 * 24 * time.Hour
+
+var ErrInvalidKey = errors.New("invalid key")
+
+type tokenVerifier interface {
+	Verify(ctx context.Context, raw string) (model.APIToken, bool, error)
+}
+
+type sessionService struct {
+	repo   *sessionRepository
+	tokens tokenVerifier
+}
+
+func NewService(repo *sessionRepository, tokens tokenVerifier) *sessionService {
+	return &sessionService{repo, tokens}
+}
+
+// Login starts a session with the scopes of the API token key, returning the
+// session ID to be stored in the cookie.
+func (s *sessionService) Login(ctx context.Context, key string) (string, model.Session, error) {
+	t, ok, err := s.tokens.Verify(ctx, key)
+	if err != nil {
+		return "", model.Session{}, fmt.Errorf("verify token: %w", err)
+	}
+	if !ok {
+		return "", model.Session{}, ErrInvalidKey
+	}
+
+	id, err := helper.RandomString(32)
+	if err != nil {
+		return "", model.Session{}, fmt.Errorf("generate session id: %w", err)
+	}
+	csrfToken, err := helper.RandomString(32)
+	if err != nil {
+		return "", model.Session{}, fmt.Errorf("generate csrf token: %w", err)
+	}
+	now := time.Now().UTC()
+	sess := model.Session{
+		Scopes:    t.Scopes,
+		CSRFToken: csrfToken,
+		ExpiresAt: now.Add(sessionDuration),
+	}
+
+	err = s.repo.DeleteExpired(ctx, now)
+	if err != nil {
+		return "", model.Session{}, fmt.Errorf("delete expired sessions: %w", err)
+	}
+	err = s.repo.Add(ctx, helper.HashSecret(id), t.ID, sess)
+	if err != nil {
+		return "", model.Session{}, fmt.Errorf("add session: %w", err)
+	}
+	return id, sess, nil
+}
+
+// Get returns the session with the given ID, and false if there is none or it
+// has expired.
+func (s *sessionService) Get(ctx context.Context, id string) (model.Session, bool, error) {
+	if id == "" {
+		return model.Session{}, false, nil
+	}
+	sess, err := s.repo.Get(ctx, helper.HashSecret(id), time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.Session{}, false, nil
+	}
+	if err != nil {
+		return model.Session{}, false, fmt.Errorf("get session: %w", err)
+	}
+	return sess, true, nil
+}
+
+func (s *sessionService) Logout(ctx context.Context, id string) error {
+	err := s.repo.Delete(ctx, helper.HashSecret(id))
+	if err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package share
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/thansetan/berak/config"
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/middleware"
+	"github.com/thansetan/berak/model"
+)
+
+type controller struct {
+	tmpl   *template.Template
+	logger *slog.Logger
+	svc    *shareService
+	cfg    config.Config
+}
+
+func NewController(svc *shareService, tmpl *template.Template, cfg config.Config, logger *slog.Logger) *controller {
+	return &controller{tmpl, logger, svc, cfg}
+}
+
+type sharesPage struct {
+	Links   []model.ShareLink
+	Session model.Session
+	Now     time.Time
+	BaseURL string
+}
+
+func (c *controller) Create(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		From      string    `json:"from"`
+		To        string    `json:"to"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		c.logger.InfoContext(r.Context(), "failed to decode share link", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.WriteMessage(w, http.StatusBadRequest, "invalid JSON format!")
+		return
+	}
+	from, err := time.Parse(dateLayout, data.From)
+	if err != nil {
+		helper.WriteMessage(w, http.StatusBadRequest, "from must be a date formatted as YYYY-MM-DD!")
+		return
+	}
+	to, err := time.Parse(dateLayout, data.To)
+	if err != nil {
+		helper.WriteMessage(w, http.StatusBadRequest, "to must be a date formatted as YYYY-MM-DD!")
+		return
+	}
+
+	l, token, err := c.svc.Create(r.Context(), from, to, data.ExpiresAt)
+	if errors.Is(err, ErrInvalidLink) {
+		helper.WriteMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to create share link", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	c.logger.InfoContext(r.Context(), "new share link created!", "id", l.ID, "from", data.From, "to", data.To, "remote_addr", r.RemoteAddr)
+	helper.WriteJSON(w, http.StatusCreated, struct {
+		model.ShareLink
+		URL string `json:"url"`
+	}{
+		ShareLink: l,
+		URL:       fmt.Sprintf("%s/share/%s", c.cfg.BaseURL, token),
+	})
+}
+
+func (c *controller) GetAll(w http.ResponseWriter, r *http.Request) {
+	links, err := c.svc.GetAll(r.Context())
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get share links", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	if links == nil {
+		links = []model.ShareLink{}
+	}
+	helper.WriteJSON(w, http.StatusOK, links)
+}
+
+func (c *controller) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		helper.WriteMessage(w, http.StatusNotFound, "share link not found!")
+		return
+	}
+	l, err := c.svc.Revoke(r.Context(), id)
+	if errors.Is(err, ErrLinkNotFound) {
+		helper.WriteMessage(w, http.StatusNotFound, "share link not found!")
+		return
+	}
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to revoke share link", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	c.logger.InfoContext(r.Context(), "share link revoked!", "id", l.ID, "remote_addr", r.RemoteAddr)
+	helper.WriteJSON(w, http.StatusOK, l)
+}
+
+// Open remembers the share link in a cookie, so the shared pages can be
+// browsed like any other, and sends the viewer to the first shared month.
+func (c *controller) Open(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	l, ok, err := c.svc.Verify(r.Context(), token)
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to verify share link", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		err = c.tmpl.ExecuteTemplate(w, "404", nil)
+		if err != nil {
+			c.logger.ErrorContext(r.Context(), "failed to execute 404 template", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		}
+		return
+	}
+
+	http.SetCookie(w, helper.NewCookie(middleware.ShareCookieName, token, l.ExpiresAt, c.cfg.SecureCookies()))
+	http.Redirect(w, r, fmt.Sprintf("/%d/%d", l.From.Year(), l.From.Month()), http.StatusSeeOther)
+}
+
+func (c *controller) Page(w http.ResponseWriter, r *http.Request) {
+	links, err := c.svc.GetAll(r.Context())
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get share links", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+
+	sess, _ := middleware.SessionFromContext(r.Context())
+	err = c.tmpl.ExecuteTemplate(w, "shares", sharesPage{
+		Links:   links,
+		Session: sess,
+		Now:     time.Now(),
+		BaseURL: c.cfg.BaseURL,
+	})
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to execute shares template", "error", err.Error(), "remote_addr", r.RemoteAddr)
+	}
+}
@@ -0,0 +1,77 @@
+package share
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/thansetan/berak/db"
+	"github.com/thansetan/berak/model"
+)
+
+type shareRepository struct {
+	db *db.Conn
+}
+
+func NewRepo(db *db.Conn) *shareRepository {
+	return &shareRepository{db}
+}
+
+const shareColumns = `id, from_date, to_date, expires_at, revoked_at, created_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanShareLink(s scanner) (model.ShareLink, error) {
+	var (
+		l         model.ShareLink
+		revokedAt sql.NullTime
+	)
+	err := s.Scan(&l.ID, &l.From, &l.To, &l.ExpiresAt, &revokedAt, &l.CreatedAt)
+	if err != nil {
+		return model.ShareLink{}, err
+	}
+	if revokedAt.Valid {
+		l.RevokedAt = &revokedAt.Time
+	}
+
+	return l, nil
+}
+
+func (r *shareRepository) Add(ctx context.Context, from, to, expiresAt time.Time) (model.ShareLink, error) {
+	return scanShareLink(r.db.QueryRowContext(ctx, `
+	INSERT INTO share_links(from_date, to_date, expires_at, created_at) VALUES(?, ?, ?, ?)
+	RETURNING `+shareColumns, from, to, expiresAt, time.Now().UTC()))
+}
+
+func (r *shareRepository) GetAll(ctx context.Context) ([]model.ShareLink, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT `+shareColumns+` FROM share_links ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.ShareLink
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, l)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *shareRepository) Get(ctx context.Context, id int64) (model.ShareLink, error) {
+	return scanShareLink(r.db.QueryRowContext(ctx, `
+	SELECT `+shareColumns+` FROM share_links WHERE id = ?`, id))
+}
+
+func (r *shareRepository) Revoke(ctx context.Context, id int64, now time.Time) (model.ShareLink, error) {
+	return scanShareLink(r.db.QueryRowContext(ctx, `
+	UPDATE share_links SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?
+	RETURNING `+shareColumns, now, id))
+}
@@ -0,0 +1,131 @@
+package share
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/model"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	ErrLinkNotFound = errors.New("share link not found")
+	ErrInvalidLink  = errors.New("invalid share link")
+)
+
+type shareService struct {
+	repo *shareRepository
+	// secret signs the links. Links signed with a different secret are
+	// rejected, so changing it invalidates every link.
+	secret []byte
+}
+
+// NewService returns a service signing links with secret. When secret is
+// empty, a random one is used, so links only last until the next restart.
+func NewService(repo *shareRepository, secret string) (*shareService, error) {
+	if secret == "" {
+		var err error
+		secret, err = helper.RandomString(32)
+		if err != nil {
+			return nil, fmt.Errorf("generate secret: %w", err)
+		}
+	}
+	return &shareService{repo, []byte(secret)}, nil
+}
+
+// Create stores a new link to the days from from until to and returns it
+// along with its signed token.
+func (s *shareService) Create(ctx context.Context, from, to, expiresAt time.Time) (model.ShareLink, string, error) {
+	from, to = helper.TruncateToDay(from), helper.TruncateToDay(to)
+	if to.Before(from) {
+		return model.ShareLink{}, "", fmt.Errorf("%w: to can't be before from", ErrInvalidLink)
+	}
+	if !expiresAt.After(time.Now()) {
+		return model.ShareLink{}, "", fmt.Errorf("%w: expiry must be in the future", ErrInvalidLink)
+	}
+
+	l, err := s.repo.Add(ctx, from, to, expiresAt.UTC())
+	if err != nil {
+		return model.ShareLink{}, "", fmt.Errorf("add share link: %w", err)
+	}
+	return l, s.sign(l), nil
+}
+
+func (s *shareService) GetAll(ctx context.Context) ([]model.ShareLink, error) {
+	links, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get share links: %w", err)
+	}
+	return links, nil
+}
+
+func (s *shareService) Revoke(ctx context.Context, id int64) (model.ShareLink, error) {
+	l, err := s.repo.Revoke(ctx, id, time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ShareLink{}, ErrLinkNotFound
+	}
+	if err != nil {
+		return model.ShareLink{}, fmt.Errorf("revoke share link: %w", err)
+	}
+	return l, nil
+}
+
+// Verify returns the link of token, and false if the token is forged or the
+// link is no longer active.
+func (s *shareService) Verify(ctx context.Context, token string) (model.ShareLink, bool, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return model.ShareLink{}, false, nil
+	}
+	gotSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, s.mac(payload)) {
+		return model.ShareLink{}, false, nil
+	}
+	claims, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return model.ShareLink{}, false, nil
+	}
+	id, err := strconv.ParseInt(strings.SplitN(string(claims), "|", 2)[0], 10, 64)
+	if err != nil {
+		return model.ShareLink{}, false, nil
+	}
+
+	l, err := s.repo.Get(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ShareLink{}, false, nil
+	}
+	if err != nil {
+		return model.ShareLink{}, false, fmt.Errorf("get share link: %w", err)
+	}
+	// the signature covers the range and expiry too, so a link can't be
+	// stretched by editing the row it was signed for.
+	if string(claims) != claimsOf(l) || !l.IsActive(time.Now()) {
+		return model.ShareLink{}, false, nil
+	}
+	return l, true, nil
+}
+
+func (s *shareService) sign(l model.ShareLink) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(claimsOf(l)))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+func (s *shareService) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func claimsOf(l model.ShareLink) string {
+	return fmt.Sprintf("%d|%s|%s|%d", l.ID, l.From.Format(dateLayout), l.To.Format(dateLayout), l.ExpiresAt.Unix())
+}
@@ -20,6 +20,7 @@ class SSEClient {
   constructor(url, options = {}) {
     this.url = url;
     this.onMessage = options.onMessage || (() => {});
+    this.onBadgeUnlocked = options.onBadgeUnlocked || (() => {});
     this.onError = options.onError || (() => {});
     this.eventSource = null;
     this.isConnected = false;
@@ -44,6 +45,14 @@ class SSEClient {
         this.onMessage(event);
       });
 
+      this.eventSource.addEventListener("badgeunlocked", (event) => {
+        this.onBadgeUnlocked(event);
+      });
+
+      this.eventSource.addEventListener("refresh", () => {
+        location.reload();
+      });
+
       this.eventSource.addEventListener("open", () => {
         this.isConnected = true;
         this.reconnectAttempts = 0;
@@ -165,6 +174,13 @@ const listenToPoopEvent = (period, year, month, triggerHighlight = false) => {
         highlight();
       }
     },
+    onBadgeUnlocked: (event) => {
+      const badges = JSON.parse(event.data);
+      for (const badge of badges) {
+        document.getElementById(`badge-${badge.id}`)?.classList.remove("locked");
+        showToast(`${badge.emoji} Badge unlocked: ${badge.name}!`);
+      }
+    },
     onError: (event) => {
       console.error("SSE error:", event);
     },
@@ -173,6 +189,17 @@ const listenToPoopEvent = (period, year, month, triggerHighlight = false) => {
   sseClient.connect();
 };
 
+const showToast = (message) => {
+  const toast = document.createElement("div");
+  toast.className = "toast";
+  toast.textContent = message;
+  document.body.appendChild(toast);
+  setTimeout(() => {
+    toast.style.opacity = "0";
+    setTimeout(() => toast.remove(), 500);
+  }, 5000);
+};
+
 const initCurrentTime = () => {
   const currentTimeElem = document.querySelector("#currentTime");
   if (!currentTimeElem) {
@@ -254,3 +281,75 @@ const tableToImage = (year, month) => {
   //   downloadButton.remove();
   // });
 };
+
+const ownerRequest = (method, body, path = "/berak") => {
+  const controls = document.getElementById("owner-controls");
+  return fetch(path, {
+    method,
+    headers: {
+      "Content-Type": "application/json",
+      "X-CSRF-Token": controls?.dataset.csrfToken ?? "",
+    },
+    body: body ? JSON.stringify(body) : undefined,
+  }).then(async (res) => {
+    if (!res.ok) {
+      const data = await res.json().catch(() => ({}));
+      showToast(data.message ?? `Request failed: ${res.status}`);
+      return false;
+    }
+    return res.json().catch(() => true);
+  });
+};
+
+const logPoop = (localTime) => {
+  if (localTime === "") {
+    showToast("Pick a time first!");
+    return;
+  }
+  const body = localTime
+    ? { timestamp: new Date(localTime).toISOString() }
+    : null;
+  ownerRequest("POST", body).then((ok) => ok && showToast("💩 logged!"));
+};
+
+const undoLastPoop = () => {
+  if (!confirm("Remove the last 💩?")) {
+    return;
+  }
+  ownerRequest("DELETE").then((ok) => ok && showToast("Last 💩 removed!"));
+};
+
+const createShareLink = (event) => {
+  event.preventDefault();
+  const form = event.target;
+  const days = Number(form.days.value);
+  ownerRequest(
+    "POST",
+    {
+      from: form.from.value,
+      to: form.to.value,
+      expires_at: new Date(Date.now() + days * 24 * 60 * 60 * 1000).toISOString(),
+    },
+    "/api/v1/shares",
+  ).then((link) => {
+    if (!link) {
+      return;
+    }
+    const output = document.getElementById("share-url");
+    output.hidden = false;
+    output.querySelector("input").value = link.url;
+    navigator.clipboard?.writeText(link.url).then(
+      () => showToast("Link copied!"),
+      () => {},
+    );
+  });
+};
+
+const revokeShareLink = (id) => {
+  if (!confirm("Revoke this link?")) {
+    return;
+  }
+  ownerRequest("DELETE", null, `/api/v1/shares/${id}`).then(
+    (ok) => ok && location.reload(),
+  );
+};
@@ -0,0 +1,90 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/model"
+)
+
+type controller struct {
+	logger *slog.Logger
+	svc    *tokenService
+}
+
+func NewController(svc *tokenService, logger *slog.Logger) *controller {
+	return &controller{logger, svc}
+}
+
+func (c *controller) Create(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Name      string        `json:"name"`
+		Scopes    []model.Scope `json:"scopes"`
+		ExpiresAt *time.Time    `json:"expires_at"`
+	}
+	defer r.Body.Close()
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil {
+		c.logger.InfoContext(r.Context(), "failed to decode token", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.WriteMessage(w, http.StatusBadRequest, "invalid JSON format!")
+		return
+	}
+
+	t, raw, err := c.svc.Create(r.Context(), data.Name, data.Scopes, data.ExpiresAt)
+	if errors.Is(err, ErrInvalidToken) {
+		helper.WriteMessage(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to create token", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	c.logger.InfoContext(r.Context(), "new token created!", "id", t.ID, "scopes", t.Scopes, "remote_addr", r.RemoteAddr)
+	helper.WriteJSON(w, http.StatusCreated, struct {
+		model.APIToken
+		Token string `json:"token"`
+	}{
+		APIToken: t,
+		Token:    raw,
+	})
+}
+
+func (c *controller) GetAll(w http.ResponseWriter, r *http.Request) {
+	tokens, err := c.svc.GetAll(r.Context())
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to get tokens", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	if tokens == nil {
+		tokens = []model.APIToken{}
+	}
+	helper.WriteJSON(w, http.StatusOK, tokens)
+}
+
+func (c *controller) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		helper.WriteMessage(w, http.StatusNotFound, "token not found!")
+		return
+	}
+	t, err := c.svc.Revoke(r.Context(), id)
+	if errors.Is(err, ErrTokenNotFound) {
+		helper.WriteMessage(w, http.StatusNotFound, "token not found!")
+		return
+	}
+	if err != nil {
+		c.logger.ErrorContext(r.Context(), "failed to revoke token", "error", err.Error(), "remote_addr", r.RemoteAddr)
+		helper.OurFault(w)
+		return
+	}
+	c.logger.InfoContext(r.Context(), "token revoked!", "id", t.ID, "remote_addr", r.RemoteAddr)
+	helper.WriteJSON(w, http.StatusOK, t)
+}
@@ -0,0 +1,106 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/thansetan/berak/db"
+	"github.com/thansetan/berak/model"
+)
+
+type tokenRepository struct {
+	db *db.Conn
+}
+
+func NewRepo(db *db.Conn) *tokenRepository {
+	return &tokenRepository{db}
+}
+
+const tokenColumns = `id, name, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanToken(s scanner) (model.APIToken, error) {
+	var (
+		t                                model.APIToken
+		scopes                           string
+		expiresAt, lastUsedAt, revokedAt sql.NullTime
+	)
+	err := s.Scan(&t.ID, &t.Name, &scopes, &expiresAt, &lastUsedAt, &revokedAt, &t.CreatedAt)
+	if err != nil {
+		return model.APIToken{}, err
+	}
+	for _, scope := range strings.Split(scopes, ",") {
+		t.Scopes = append(t.Scopes, model.Scope(scope))
+	}
+	if expiresAt.Valid {
+		t.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+
+	return t, nil
+}
+
+func (r *tokenRepository) Add(ctx context.Context, name, tokenHash string, scopes []model.Scope, expiresAt *time.Time) (model.APIToken, error) {
+	scopeStrs := make([]string, len(scopes))
+	for i, scope := range scopes {
+		scopeStrs[i] = string(scope)
+	}
+	return scanToken(r.db.QueryRowContext(ctx, `
+	INSERT INTO api_tokens(name, token_hash, scopes, expires_at, created_at) VALUES(?, ?, ?, ?, ?)
+	RETURNING `+tokenColumns, name, tokenHash, strings.Join(scopeStrs, ","), expiresAt, time.Now().UTC()))
+}
+
+func (r *tokenRepository) GetAll(ctx context.Context) ([]model.APIToken, error) {
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT `+tokenColumns+` FROM api_tokens ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var data []model.APIToken
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, t)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *tokenRepository) GetByHash(ctx context.Context, tokenHash string) (model.APIToken, error) {
+	return scanToken(r.db.QueryRowContext(ctx, `
+	SELECT `+tokenColumns+` FROM api_tokens WHERE token_hash = ?`, tokenHash))
+}
+
+// Touch updates the last used time of a token. It is only written when the
+// previous one is older than a minute so busy tokens don't keep writing to
+// the database file.
+func (r *tokenRepository) Touch(ctx context.Context, id int64, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+	UPDATE api_tokens SET last_used_at = ?
+	WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)`, now, id, now.Add(-time.Minute))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *tokenRepository) Revoke(ctx context.Context, id int64, now time.Time) (model.APIToken, error) {
+	return scanToken(r.db.QueryRowContext(ctx, `
+	UPDATE api_tokens SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?
+	RETURNING `+tokenColumns, now, id))
+}
@@ -0,0 +1,111 @@
+package token
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thansetan/berak/helper"
+	"github.com/thansetan/berak/model"
+)
+
+const tokenPrefix = "berak_"
+
+var (
+	ErrTokenNotFound = errors.New("token not found")
+	ErrInvalidToken  = errors.New("invalid token")
+)
+
+type tokenService struct {
+	repo *tokenRepository
+	// masterKey is accepted as an admin token so the first token can be
+	// created. It is disabled when empty.
+	masterKey string
+}
+
+func NewService(repo *tokenRepository, masterKey string) *tokenService {
+	return &tokenService{repo, masterKey}
+}
+
+// Create stores a new token and returns it along with its raw value, which
+// can't be retrieved again afterwards.
+func (s *tokenService) Create(ctx context.Context, name string, scopes []model.Scope, expiresAt *time.Time) (model.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return model.APIToken{}, "", fmt.Errorf("%w: name can't be empty", ErrInvalidToken)
+	}
+	if len(scopes) == 0 {
+		return model.APIToken{}, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidToken)
+	}
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return model.APIToken{}, "", fmt.Errorf("%w: unknown scope %q", ErrInvalidToken, scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return model.APIToken{}, "", fmt.Errorf("%w: expiry must be in the future", ErrInvalidToken)
+	}
+
+	raw, err := helper.RandomString(32)
+	if err != nil {
+		return model.APIToken{}, "", fmt.Errorf("generate token: %w", err)
+	}
+	raw = tokenPrefix + raw
+
+	t, err := s.repo.Add(ctx, name, helper.HashSecret(raw), scopes, expiresAt)
+	if err != nil {
+		return model.APIToken{}, "", fmt.Errorf("add token: %w", err)
+	}
+	return t, raw, nil
+}
+
+func (s *tokenService) GetAll(ctx context.Context) ([]model.APIToken, error) {
+	tokens, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (s *tokenService) Revoke(ctx context.Context, id int64) (model.APIToken, error) {
+	t, err := s.repo.Revoke(ctx, id, time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.APIToken{}, ErrTokenNotFound
+	}
+	if err != nil {
+		return model.APIToken{}, fmt.Errorf("revoke token: %w", err)
+	}
+	return t, nil
+}
+
+// Verify returns the token matching raw, and false if there is none or it's
+// no longer active.
+func (s *tokenService) Verify(ctx context.Context, raw string) (model.APIToken, bool, error) {
+	if raw == "" {
+		return model.APIToken{}, false, nil
+	}
+	if s.masterKey != "" && subtle.ConstantTimeCompare([]byte(raw), []byte(s.masterKey)) == 1 {
+		return model.APIToken{Name: "master key", Scopes: []model.Scope{model.ScopeAdmin}}, true, nil
+	}
+
+	t, err := s.repo.GetByHash(ctx, helper.HashSecret(raw))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.APIToken{}, false, nil
+	}
+	if err != nil {
+		return model.APIToken{}, false, fmt.Errorf("get token: %w", err)
+	}
+	now := time.Now().UTC()
+	if !t.IsActive(now) {
+		return model.APIToken{}, false, nil
+	}
+	err = s.repo.Touch(ctx, t.ID, now)
+	if err != nil {
+		return model.APIToken{}, false, fmt.Errorf("touch token: %w", err)
+	}
+	return t, true, nil
+}